@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/yasufadhili/jawt/internal/build"
+	"github.com/yasufadhili/jawt/internal/core"
+	"os"
+	"text/tabwriter"
+)
+
+var outputDir string
+var buildMode string
+var printConfig bool
+var asLib bool
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build the project for production",
+	Long: `Compiles every page and component, pre-renders the pages and writes a
+self-contained site to the dist directory, ready to deploy.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		var logLevel core.LogLevel
+		if verbose {
+			logLevel = core.DebugLevel
+		} else {
+			logLevel = core.WarnLevel
+		}
+		logger := core.NewDefaultLogger(logLevel)
+
+		projectDir, err := os.Getwd()
+		if err != nil {
+			logger.Error("Failed to get current working directory", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		cfg, err := core.LoadJawtConfig("")
+		if err != nil {
+			logger.Error("Failed to load JAWT configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			logger.Error("Invalid Jawt configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		projectConfig, sources, err := core.LoadProjectConfigForMode(projectDir, buildMode)
+		if err != nil {
+			logger.Error("Failed to load project configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		if printConfig {
+			printProjectConfig(projectConfig, sources)
+			return
+		}
+
+		if err := projectConfig.Validate(); err != nil {
+			logger.Error("Invalid project configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		paths, err := core.NewProjectPaths(projectDir, projectConfig, cfg)
+		if err != nil {
+			logger.Error("Failed to initialise project paths", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		if err := paths.EnsureDirectories(); err != nil {
+			logger.Error("Failed to create project directories", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		buildOptions := core.NewBuildOptions()
+		buildOptions.Mode = buildMode
+
+		ctx := core.NewJawtContext(cfg, projectConfig, paths, logger, buildOptions)
+
+		if asLib || projectConfig.IsLibrary() {
+			err = build.BuildLibrary(ctx, outputDir)
+		} else {
+			err = build.BuildProject(ctx, outputDir)
+		}
+		if err != nil {
+			logger.Error("Build failed", core.ErrorField(err))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	buildCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Write the build to this directory instead of the configured dist directory")
+	buildCmd.Flags().BoolVar(&asLib, "as-lib", false, "Build the exports of the project as a library package, whatever its type")
+	buildCmd.Flags().StringVar(&buildMode, "mode", "production", "Build mode; merges jawt.project.<mode>.json over the project configuration")
+	buildCmd.Flags().BoolVar(&printConfig, "print-config", false, "Print the effective configuration and where each value comes from, then exit")
+	buildCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+}
+
+// printProjectConfig writes every value of the effective configuration with
+// the file that set it.
+func printProjectConfig(config *core.ProjectConfig, sources core.ConfigSources) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, v := range config.Values(sources) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+	}
+	w.Flush()
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/yasufadhili/jawt/internal/build"
+	"github.com/yasufadhili/jawt/internal/core"
+	"os"
+)
+
+var (
+	graphOptions build.GraphOptions
+	graphWhy     bool
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [--why <from> <to>]",
+	Short: "Print the dependency graph of the project",
+	Long: `Prints which pages and components import which, as Graphviz DOT, Mermaid
+or JSON. Filters show the part of the graph a document depends on, what
+depends on it and would be rebuilt when it changes, or why one document
+depends on another.`,
+	Example: `  jawt graph | dot -Tsvg > graph.svg
+  jawt graph --format mermaid --pages
+  jawt graph --from components/layout --reverse
+  jawt graph --why app/index components/button`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if graphWhy {
+			return cobra.ExactArgs(2)(cmd, args)
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if graphWhy {
+			graphOptions.Why = args
+		}
+
+		var logLevel core.LogLevel
+		if verbose {
+			logLevel = core.DebugLevel
+		} else {
+			logLevel = core.WarnLevel
+		}
+		logger := core.NewDefaultLogger(logLevel)
+
+		projectDir, err := os.Getwd()
+		if err != nil {
+			logger.Error("Failed to get current working directory", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		cfg, err := core.LoadJawtConfig("")
+		if err != nil {
+			logger.Error("Failed to load JAWT configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		projectConfig, err := core.LoadProjectConfig(projectDir)
+		if err != nil {
+			logger.Error("Failed to load project configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		paths, err := core.NewProjectPaths(projectDir, projectConfig, cfg)
+		if err != nil {
+			logger.Error("Failed to initialise project paths", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		ctx := core.NewJawtContext(cfg, projectConfig, paths, logger, core.NewBuildOptions())
+
+		if err := build.ExportGraph(ctx, os.Stdout, graphOptions); err != nil {
+			logger.Error("Failed to export dependency graph", core.ErrorField(err))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringVarP(&graphOptions.Format, "format", "f", "dot", "Output format: dot, mermaid or json")
+	graphCmd.Flags().StringVar(&graphOptions.From, "from", "", "Only show this document and what it depends on")
+	graphCmd.Flags().BoolVar(&graphOptions.Reverse, "reverse", false, "With --from, show what depends on the document instead")
+	graphCmd.Flags().BoolVar(&graphOptions.PagesOnly, "pages", false, "Only show pages and the components they use")
+	graphCmd.Flags().BoolVar(&graphWhy, "why", false, "Show the shortest chain of imports from one document to another")
+	graphCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+}
@@ -30,7 +30,9 @@ func init() {
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(runCmd)
-	// rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(unusedCmd)
 	// rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(tscCmd)
 	// rootCmd.AddCommand(debugCmd)
@@ -10,6 +10,7 @@ import (
 var port int
 var clearCache bool
 var verbose bool
+var runMode string
 
 var runCmd = &cobra.Command{
 	Use:   "run",
@@ -43,12 +44,17 @@ Monitors your JML files for changes and automatically reloads the browser.`,
 			os.Exit(1)
 		}
 
-		projectConfig, err := core.LoadProjectConfig(projectDir)
+		projectConfig, sources, err := core.LoadProjectConfigForMode(projectDir, runMode)
 		if err != nil {
 			logger.Error("Failed to load project configuration", core.ErrorField(err))
 			os.Exit(1)
 		}
 
+		if printConfig {
+			printProjectConfig(projectConfig, sources)
+			return
+		}
+
 		if err := projectConfig.Validate(); err != nil {
 			logger.Error("Invalid project configuration", core.ErrorField(err))
 			os.Exit(1)
@@ -66,6 +72,7 @@ Monitors your JML files for changes and automatically reloads the browser.`,
 		}
 
 		buildOptions := core.NewBuildOptions()
+		buildOptions.Mode = runMode
 
 		if port != 6500 {
 			projectConfig.SetDevServerPort(port)
@@ -106,5 +113,7 @@ Monitors your JML files for changes and automatically reloads the browser.`,
 func init() {
 	runCmd.Flags().IntVarP(&port, "port", "p", 6500, "Specify custom port for the development server")
 	runCmd.Flags().BoolVarP(&clearCache, "clear-cache", "c", false, "Run with cleared cache (not yet implemented)")
+	runCmd.Flags().StringVar(&runMode, "mode", "development", "Run mode; merges jawt.project.<mode>.json over the project configuration")
+	runCmd.Flags().BoolVar(&printConfig, "print-config", false, "Print the effective configuration and where each value comes from, then exit")
 	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
 }
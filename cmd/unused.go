@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/yasufadhili/jawt/internal/build"
+	"github.com/yasufadhili/jawt/internal/core"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	unusedDelete bool
+	unusedDryRun bool
+)
+
+var unusedCmd = &cobra.Command{
+	Use:   "unused",
+	Short: "List components and scripts that nothing uses",
+	Long: `Lists the components that no page uses, directly or through other
+components, and the scripts that no page or component in use imports.
+Scripts named in preBuild and postBuild commands or loaded by an HTML
+file in the project count as used. With --delete, asks before deleting
+each one.`,
+	Example: `  jawt unused
+  jawt unused --delete --dry-run
+  jawt unused --delete`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		var logLevel core.LogLevel
+		if verbose {
+			logLevel = core.DebugLevel
+		} else {
+			logLevel = core.WarnLevel
+		}
+		logger := core.NewDefaultLogger(logLevel)
+
+		projectDir, err := os.Getwd()
+		if err != nil {
+			logger.Error("Failed to get current working directory", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		cfg, err := core.LoadJawtConfig("")
+		if err != nil {
+			logger.Error("Failed to load JAWT configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		projectConfig, err := core.LoadProjectConfig(projectDir)
+		if err != nil {
+			logger.Error("Failed to load project configuration", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		paths, err := core.NewProjectPaths(projectDir, projectConfig, cfg)
+		if err != nil {
+			logger.Error("Failed to initialise project paths", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		ctx := core.NewJawtContext(cfg, projectConfig, paths, logger, core.NewBuildOptions())
+
+		report, err := build.FindUnused(ctx)
+		if err != nil {
+			logger.Error("Failed to find unused files", core.ErrorField(err))
+			os.Exit(1)
+		}
+
+		if report.Empty() {
+			fmt.Println("Every component and script is in use.")
+			return
+		}
+
+		rel := func(path string) string {
+			return filepath.ToSlash(paths.GetRelativePath(path))
+		}
+
+		if !unusedDelete {
+			printUnused("Unused components:", report.Components, rel)
+			printUnused("Unused scripts:", report.Scripts, rel)
+			return
+		}
+
+		if unusedDryRun {
+			for _, path := range report.Files() {
+				fmt.Printf("Would delete %s\n", rel(path))
+			}
+			return
+		}
+
+		in := bufio.NewReader(os.Stdin)
+		deleted := 0
+		for _, path := range report.Files() {
+			fmt.Printf("Delete %s? [y/N] ", rel(path))
+			answer, err := in.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer == "y" || answer == "yes" {
+				if err := os.Remove(path); err != nil {
+					logger.Error("Failed to delete file", core.StringField("path", path), core.ErrorField(err))
+					continue
+				}
+				deleted++
+			}
+			if err != nil {
+				// Input ended; keep the remaining files
+				fmt.Println()
+				break
+			}
+		}
+		fmt.Printf("Deleted %d of %d unused files.\n", deleted, len(report.Files()))
+	},
+}
+
+func printUnused(title string, files []string, rel func(string) string) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Println(title)
+	for _, path := range files {
+		fmt.Printf("  %s\n", rel(path))
+	}
+}
+
+func init() {
+	unusedCmd.Flags().BoolVar(&unusedDelete, "delete", false, "Ask before deleting each unused file")
+	unusedCmd.Flags().BoolVar(&unusedDryRun, "dry-run", false, "With --delete, list the files that would be deleted without asking")
+	unusedCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+}
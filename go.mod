@@ -4,6 +4,7 @@ go 1.24.4
 
 require (
 	github.com/antlr4-go/antlr/v4 v4.13.1
+	github.com/evanw/esbuild v0.28.2
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/gorilla/websocket v1.5.3
 	github.com/spf13/cobra v1.9.1
@@ -1,5 +1,167 @@
 grammar Jml;
 
+// Parser rules
+
 document
-    : 
-;
\ No newline at end of file
+    : doctypeDeclaration importStatement* element EOF
+    ;
+
+doctypeDeclaration
+    : DOCTYPE (PAGE | COMPONENT) IDENTIFIER
+    ;
+
+importStatement
+    : IMPORT LAZY? COMPONENT IDENTIFIER FROM STRING
+    | IMPORT SCRIPT IDENTIFIER FROM STRING
+    | IMPORT IDENTIFIER
+    ;
+
+element
+    : IDENTIFIER block
+    ;
+
+block
+    : LBRACE elementMember* RBRACE
+    ;
+
+elementMember
+    : property
+    | element
+    | forLoop
+    | ifBlock
+    ;
+
+property
+    : IDENTIFIER COLON expression
+    ;
+
+forLoop
+    : FOR LPAREN IDENTIFIER IN expression RPAREN block
+    ;
+
+ifBlock
+    : IF LPAREN expression RPAREN block elseClause?
+    ;
+
+elseClause
+    : ELSE (ifBlock | block)
+    ;
+
+expression
+    : expression DOT IDENTIFIER                                     # MemberExpression
+    | expression LBRACKET expression RBRACKET                       # IndexExpression
+    | expression LPAREN arguments? RPAREN                           # CallExpression
+    | op=(NOT | MINUS) expression                                   # UnaryExpression
+    | expression op=(STAR | SLASH | PERCENT) expression             # BinaryExpression
+    | expression op=(PLUS | MINUS) expression                       # BinaryExpression
+    | expression op=(LT | GT | LE | GE) expression                  # BinaryExpression
+    | expression op=(EQ | NEQ | STRICT_EQ | STRICT_NEQ) expression  # BinaryExpression
+    | expression op=AND expression                                  # BinaryExpression
+    | expression op=OR expression                                   # BinaryExpression
+    | <assoc=right> expression QUESTION expression COLON expression # ConditionalExpression
+    | arrowFunction                                                 # ArrowFunctionExpression
+    | LPAREN expression RPAREN                                      # ParenthesizedExpression
+    | arrayLiteral                                                  # ArrayExpression
+    | objectLiteral                                                 # ObjectExpression
+    | literal                                                       # LiteralExpression
+    | IDENTIFIER                                                    # IdentifierExpression
+    ;
+
+arrowFunction
+    : (IDENTIFIER | LPAREN parameterList? RPAREN) ARROW expression
+    ;
+
+parameterList
+    : IDENTIFIER (COMMA IDENTIFIER)*
+    ;
+
+arguments
+    : expression (COMMA expression)*
+    ;
+
+arrayLiteral
+    : LBRACKET (expression (COMMA expression)* COMMA?)? RBRACKET
+    ;
+
+objectLiteral
+    : LBRACE (objectProperty (COMMA objectProperty)* COMMA?)? RBRACE
+    ;
+
+objectProperty
+    : (IDENTIFIER | STRING) COLON expression
+    ;
+
+literal
+    : STRING
+    | TEMPLATE_STRING
+    | NUMBER
+    | TRUE
+    | FALSE
+    | NULL
+    ;
+
+// Lexer rules
+
+DOCTYPE   : '_doctype';
+PAGE      : 'page';
+COMPONENT : 'component';
+SCRIPT    : 'script';
+IMPORT    : 'import';
+LAZY      : 'lazy';
+FROM      : 'from';
+FOR       : 'for';
+IN        : 'in';
+IF        : 'if';
+ELSE      : 'else';
+TRUE      : 'true';
+FALSE     : 'false';
+NULL      : 'null';
+
+ARROW      : '=>';
+STRICT_EQ  : '===';
+STRICT_NEQ : '!==';
+EQ         : '==';
+NEQ        : '!=';
+LE         : '<=';
+GE         : '>=';
+LT         : '<';
+GT         : '>';
+AND        : '&&';
+OR         : '||';
+NOT        : '!';
+PLUS       : '+';
+MINUS      : '-';
+STAR       : '*';
+SLASH      : '/';
+PERCENT    : '%';
+QUESTION   : '?';
+COLON      : ':';
+COMMA      : ',';
+DOT        : '.';
+LPAREN     : '(';
+RPAREN     : ')';
+LBRACE     : '{';
+RBRACE     : '}';
+LBRACKET   : '[';
+RBRACKET   : ']';
+
+STRING
+    : '"' (~["\\\r\n] | '\\' .)* '"'
+    | '\'' (~['\\\r\n] | '\\' .)* '\''
+    ;
+
+TEMPLATE_STRING
+    : '`' (~[`\\] | '\\' .)* '`'
+    ;
+
+NUMBER
+    : [0-9]+ ('.' [0-9]+)?
+    ;
+
+IDENTIFIER
+    : [a-zA-Z_$] [a-zA-Z0-9_$]*
+    ;
+
+LINE_COMMENT  : '//' ~[\r\n]* -> skip;
+BLOCK_COMMENT : '/*' .*? '*/' -> skip;
+WS            : [ \t\r\n]+ -> skip;
@@ -1,3 +1,475 @@
 package ast
 
-type Document struct{}
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Position is the location of a node in its source file.
+type Position struct {
+	Line   int
+	Column int
+	File   string
+}
+
+// Pos returns the position itself so that embedding Position satisfies Node.Pos.
+func (p Position) Pos() Position {
+	return p
+}
+
+// Node is implemented by every node in the AST.
+type Node interface {
+	Pos() Position
+	String() string
+	Accept(v Visitor)
+}
+
+// Expression is a node that produces a value.
+type Expression interface {
+	Node
+	expressionNode()
+}
+
+// DocType distinguishes pages from components.
+type DocType int
+
+const (
+	DocTypePage DocType = iota
+	DocTypeComponent
+)
+
+// String returns the keyword used for the doctype in JML.
+func (d DocType) String() string {
+	switch d {
+	case DocTypePage:
+		return "page"
+	case DocTypeComponent:
+		return "component"
+	default:
+		return "unknown"
+	}
+}
+
+// Document is a single JML file, either a page or a component.
+type Document struct {
+	Position
+	DocType    DocType
+	Name       string
+	Imports    []*Import
+	Root       *Element
+	SourceFile string
+}
+
+func (d *Document) Accept(v Visitor) { v.VisitDocument(d) }
+
+func (d *Document) String() string {
+	return fmt.Sprintf("_doctype %s %s", d.DocType, d.Name)
+}
+
+// ImportKind identifies what an import statement brings into scope.
+type ImportKind int
+
+const (
+	ImportComponent ImportKind = iota
+	ImportScript
+	ImportBuiltin
+)
+
+// String returns the keyword used for the import kind in JML.
+func (k ImportKind) String() string {
+	switch k {
+	case ImportComponent:
+		return "component"
+	case ImportScript:
+		return "script"
+	case ImportBuiltin:
+		return "builtin"
+	default:
+		return "unknown"
+	}
+}
+
+// Import is an import statement. Builtin imports (`import browser`) have no Path.
+type Import struct {
+	Position
+	Kind ImportKind
+	Name string
+	Path string
+
+	// Lazy component imports are loaded after the importing module rather
+	// than before it. They are not dependencies for compilation order, which
+	// lets a component use itself or another component that uses it.
+	Lazy bool
+
+	// ResolvedPath is the absolute file the import refers to. It is filled
+	// in by the build system once the import has been resolved.
+	ResolvedPath string
+}
+
+func (i *Import) Accept(v Visitor) { v.VisitImport(i) }
+
+func (i *Import) String() string {
+	if i.Kind == ImportBuiltin {
+		return "import " + i.Name
+	}
+	if i.Lazy {
+		return fmt.Sprintf("import lazy %s %s from %q", i.Kind, i.Name, i.Path)
+	}
+	return fmt.Sprintf("import %s %s from %q", i.Kind, i.Name, i.Path)
+}
+
+// Element is a built-in or user component used in a UI tree, e.g. `Container { ... }`.
+type Element struct {
+	Position
+	Name       string
+	Properties []*Property
+	Children   []Node // *Element, *ForLoop or *IfBlock
+}
+
+func (e *Element) Accept(v Visitor) { v.VisitElement(e) }
+
+func (e *Element) String() string {
+	return e.Name + " { ... }"
+}
+
+// Property returns the property with the given name, or nil.
+func (e *Element) Property(name string) *Property {
+	for _, p := range e.Properties {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Property is a `name: value` assignment inside an element.
+type Property struct {
+	Position
+	Name  string
+	Value Expression
+}
+
+func (p *Property) Accept(v Visitor) { v.VisitProperty(p) }
+
+func (p *Property) String() string {
+	return p.Name + ": " + p.Value.String()
+}
+
+// ForLoop renders its body once for every item of Iterable.
+type ForLoop struct {
+	Position
+	Variable string
+	Iterable Expression
+	Body     []Node
+}
+
+func (f *ForLoop) Accept(v Visitor) { v.VisitForLoop(f) }
+
+func (f *ForLoop) String() string {
+	return fmt.Sprintf("for (%s in %s) { ... }", f.Variable, f.Iterable)
+}
+
+// IfBlock renders Then when Condition is truthy and Else otherwise.
+// An `else if` chain is represented as an Else holding a single *IfBlock.
+type IfBlock struct {
+	Position
+	Condition Expression
+	Then      []Node
+	Else      []Node
+}
+
+func (i *IfBlock) Accept(v Visitor) { v.VisitIfBlock(i) }
+
+func (i *IfBlock) String() string {
+	return fmt.Sprintf("if (%s) { ... }", i.Condition)
+}
+
+// Expressions
+
+// Identifier is a bare name such as `props` or `todos`.
+type Identifier struct {
+	Position
+	Name string
+}
+
+func (i *Identifier) Accept(v Visitor) { v.VisitIdentifier(i) }
+func (i *Identifier) String() string   { return i.Name }
+func (*Identifier) expressionNode()    {}
+
+// StringLiteral is a single or double-quoted string.
+type StringLiteral struct {
+	Position
+	Value string
+}
+
+func (s *StringLiteral) Accept(v Visitor) { v.VisitStringLiteral(s) }
+func (s *StringLiteral) String() string   { return QuoteString(s.Value) }
+func (*StringLiteral) expressionNode()    {}
+
+// TemplateLiteral is a backtick string. Quasis always has one more entry
+// than Expressions: `a${x}b` has Quasis ["a", "b"] and Expressions [x].
+type TemplateLiteral struct {
+	Position
+	Quasis      []string
+	Expressions []Expression
+}
+
+func (t *TemplateLiteral) Accept(v Visitor) { v.VisitTemplateLiteral(t) }
+func (*TemplateLiteral) expressionNode()    {}
+
+func (t *TemplateLiteral) String() string {
+	var sb strings.Builder
+	sb.WriteByte('`')
+	for i, q := range t.Quasis {
+		sb.WriteString(EscapeTemplateText(q))
+		if i < len(t.Expressions) {
+			sb.WriteString("${")
+			sb.WriteString(t.Expressions[i].String())
+			sb.WriteString("}")
+		}
+	}
+	sb.WriteByte('`')
+	return sb.String()
+}
+
+// EscapeTemplateText escapes text so that it can be placed verbatim inside
+// a TypeScript template literal.
+func EscapeTemplateText(s string) string {
+	r := strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${")
+	return r.Replace(s)
+}
+
+// QuoteString returns s as a double-quoted TypeScript string literal. Go's
+// strconv.Quote isn't used because escapes such as \a and \U0001F600 mean
+// something else, or nothing, in JavaScript.
+func QuoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\u2028', '\u2029':
+			// Line terminators, which older engines reject in string literals
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// NumberLiteral is a numeric literal.
+type NumberLiteral struct {
+	Position
+	Value float64
+	Raw   string
+}
+
+func (n *NumberLiteral) Accept(v Visitor) { v.VisitNumberLiteral(n) }
+func (n *NumberLiteral) String() string   { return n.Raw }
+func (*NumberLiteral) expressionNode()    {}
+
+// BooleanLiteral is `true` or `false`.
+type BooleanLiteral struct {
+	Position
+	Value bool
+}
+
+func (b *BooleanLiteral) Accept(v Visitor) { v.VisitBooleanLiteral(b) }
+func (b *BooleanLiteral) String() string   { return strconv.FormatBool(b.Value) }
+func (*BooleanLiteral) expressionNode()    {}
+
+// NullLiteral is `null`.
+type NullLiteral struct {
+	Position
+}
+
+func (n *NullLiteral) Accept(v Visitor) { v.VisitNullLiteral(n) }
+func (n *NullLiteral) String() string   { return "null" }
+func (*NullLiteral) expressionNode()    {}
+
+// ArrayLiteral is `[a, b, c]`.
+type ArrayLiteral struct {
+	Position
+	Elements []Expression
+}
+
+func (a *ArrayLiteral) Accept(v Visitor) { v.VisitArrayLiteral(a) }
+func (*ArrayLiteral) expressionNode()    {}
+
+func (a *ArrayLiteral) String() string {
+	parts := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		parts[i] = e.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// ObjectLiteral is `{ key: value, "other-key": value }`.
+type ObjectLiteral struct {
+	Position
+	Properties []*ObjectProperty
+}
+
+func (o *ObjectLiteral) Accept(v Visitor) { v.VisitObjectLiteral(o) }
+func (*ObjectLiteral) expressionNode()    {}
+
+func (o *ObjectLiteral) String() string {
+	if len(o.Properties) == 0 {
+		return "{}"
+	}
+	parts := make([]string, len(o.Properties))
+	for i, p := range o.Properties {
+		parts[i] = p.String()
+	}
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+// ObjectProperty is a single key/value pair in an object literal.
+type ObjectProperty struct {
+	Position
+	Key   string
+	Value Expression
+}
+
+func (p *ObjectProperty) Accept(v Visitor) { v.VisitObjectProperty(p) }
+
+func (p *ObjectProperty) String() string {
+	if isIdentifierName(p.Key) {
+		return p.Key + ": " + p.Value.String()
+	}
+	return QuoteString(p.Key) + ": " + p.Value.String()
+}
+
+// MemberExpression is `object.property`.
+type MemberExpression struct {
+	Position
+	Object   Expression
+	Property string
+}
+
+func (m *MemberExpression) Accept(v Visitor) { v.VisitMemberExpression(m) }
+func (m *MemberExpression) String() string   { return m.Object.String() + "." + m.Property }
+func (*MemberExpression) expressionNode()    {}
+
+// IndexExpression is `object[index]`.
+type IndexExpression struct {
+	Position
+	Object Expression
+	Index  Expression
+}
+
+func (i *IndexExpression) Accept(v Visitor) { v.VisitIndexExpression(i) }
+func (i *IndexExpression) String() string   { return i.Object.String() + "[" + i.Index.String() + "]" }
+func (*IndexExpression) expressionNode()    {}
+
+// CallExpression is `callee(arg, ...)`.
+type CallExpression struct {
+	Position
+	Callee    Expression
+	Arguments []Expression
+}
+
+func (c *CallExpression) Accept(v Visitor) { v.VisitCallExpression(c) }
+func (*CallExpression) expressionNode()    {}
+
+func (c *CallExpression) String() string {
+	args := make([]string, len(c.Arguments))
+	for i, a := range c.Arguments {
+		args[i] = a.String()
+	}
+	return c.Callee.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// UnaryExpression is `!operand` or `-operand`.
+type UnaryExpression struct {
+	Position
+	Operator string
+	Operand  Expression
+}
+
+func (u *UnaryExpression) Accept(v Visitor) { v.VisitUnaryExpression(u) }
+func (*UnaryExpression) expressionNode()    {}
+
+func (u *UnaryExpression) String() string {
+	// -(-x) written as --x would be a decrement
+	if _, ok := u.Operand.(*UnaryExpression); ok {
+		return u.Operator + "(" + u.Operand.String() + ")"
+	}
+	return u.Operator + u.Operand.String()
+}
+
+// BinaryExpression covers arithmetic, comparison and logical operators.
+type BinaryExpression struct {
+	Position
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+func (b *BinaryExpression) Accept(v Visitor) { v.VisitBinaryExpression(b) }
+func (*BinaryExpression) expressionNode()    {}
+
+func (b *BinaryExpression) String() string {
+	return "(" + b.Left.String() + " " + b.Operator + " " + b.Right.String() + ")"
+}
+
+// ConditionalExpression is `test ? consequent : alternate`.
+type ConditionalExpression struct {
+	Position
+	Test       Expression
+	Consequent Expression
+	Alternate  Expression
+}
+
+func (c *ConditionalExpression) Accept(v Visitor) { v.VisitConditionalExpression(c) }
+func (*ConditionalExpression) expressionNode()    {}
+
+func (c *ConditionalExpression) String() string {
+	return "(" + c.Test.String() + " ? " + c.Consequent.String() + " : " + c.Alternate.String() + ")"
+}
+
+// ArrowFunction is `(a, b) => body`. Only expression bodies are supported.
+type ArrowFunction struct {
+	Position
+	Params []string
+	Body   Expression
+}
+
+func (a *ArrowFunction) Accept(v Visitor) { v.VisitArrowFunction(a) }
+func (*ArrowFunction) expressionNode()    {}
+
+func (a *ArrowFunction) String() string {
+	return "(" + strings.Join(a.Params, ", ") + ") => " + a.Body.String()
+}
+
+func isIdentifierName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || r == '$':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package ast
+
+// Visitor has a Visit method for every node type in the AST.
+// Embed BaseVisitor and override only the methods you need.
+type Visitor interface {
+	VisitDocument(n *Document)
+	VisitImport(n *Import)
+	VisitElement(n *Element)
+	VisitProperty(n *Property)
+	VisitForLoop(n *ForLoop)
+	VisitIfBlock(n *IfBlock)
+
+	VisitIdentifier(n *Identifier)
+	VisitStringLiteral(n *StringLiteral)
+	VisitTemplateLiteral(n *TemplateLiteral)
+	VisitNumberLiteral(n *NumberLiteral)
+	VisitBooleanLiteral(n *BooleanLiteral)
+	VisitNullLiteral(n *NullLiteral)
+	VisitArrayLiteral(n *ArrayLiteral)
+	VisitObjectLiteral(n *ObjectLiteral)
+	VisitObjectProperty(n *ObjectProperty)
+	VisitMemberExpression(n *MemberExpression)
+	VisitIndexExpression(n *IndexExpression)
+	VisitCallExpression(n *CallExpression)
+	VisitUnaryExpression(n *UnaryExpression)
+	VisitBinaryExpression(n *BinaryExpression)
+	VisitConditionalExpression(n *ConditionalExpression)
+	VisitArrowFunction(n *ArrowFunction)
+}
+
+// BaseVisitor implements Visitor with no-op methods.
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitDocument(*Document)                           {}
+func (BaseVisitor) VisitImport(*Import)                               {}
+func (BaseVisitor) VisitElement(*Element)                             {}
+func (BaseVisitor) VisitProperty(*Property)                           {}
+func (BaseVisitor) VisitForLoop(*ForLoop)                             {}
+func (BaseVisitor) VisitIfBlock(*IfBlock)                             {}
+func (BaseVisitor) VisitIdentifier(*Identifier)                       {}
+func (BaseVisitor) VisitStringLiteral(*StringLiteral)                 {}
+func (BaseVisitor) VisitTemplateLiteral(*TemplateLiteral)             {}
+func (BaseVisitor) VisitNumberLiteral(*NumberLiteral)                 {}
+func (BaseVisitor) VisitBooleanLiteral(*BooleanLiteral)               {}
+func (BaseVisitor) VisitNullLiteral(*NullLiteral)                     {}
+func (BaseVisitor) VisitArrayLiteral(*ArrayLiteral)                   {}
+func (BaseVisitor) VisitObjectLiteral(*ObjectLiteral)                 {}
+func (BaseVisitor) VisitObjectProperty(*ObjectProperty)               {}
+func (BaseVisitor) VisitMemberExpression(*MemberExpression)           {}
+func (BaseVisitor) VisitIndexExpression(*IndexExpression)             {}
+func (BaseVisitor) VisitCallExpression(*CallExpression)               {}
+func (BaseVisitor) VisitUnaryExpression(*UnaryExpression)             {}
+func (BaseVisitor) VisitBinaryExpression(*BinaryExpression)           {}
+func (BaseVisitor) VisitConditionalExpression(*ConditionalExpression) {}
+func (BaseVisitor) VisitArrowFunction(*ArrowFunction)                 {}
@@ -0,0 +1,75 @@
+package ast
+
+// Walk visits node and then, depth-first, every node below it.
+func Walk(v Visitor, node Node) {
+	if node == nil {
+		return
+	}
+
+	node.Accept(v)
+
+	switch n := node.(type) {
+	case *Document:
+		for _, imp := range n.Imports {
+			Walk(v, imp)
+		}
+		if n.Root != nil {
+			Walk(v, n.Root)
+		}
+	case *Element:
+		for _, p := range n.Properties {
+			Walk(v, p)
+		}
+		walkList(v, n.Children)
+	case *Property:
+		Walk(v, n.Value)
+	case *ForLoop:
+		Walk(v, n.Iterable)
+		walkList(v, n.Body)
+	case *IfBlock:
+		Walk(v, n.Condition)
+		walkList(v, n.Then)
+		walkList(v, n.Else)
+	case *TemplateLiteral:
+		for _, e := range n.Expressions {
+			Walk(v, e)
+		}
+	case *ArrayLiteral:
+		for _, e := range n.Elements {
+			Walk(v, e)
+		}
+	case *ObjectLiteral:
+		for _, p := range n.Properties {
+			Walk(v, p)
+		}
+	case *ObjectProperty:
+		Walk(v, n.Value)
+	case *MemberExpression:
+		Walk(v, n.Object)
+	case *IndexExpression:
+		Walk(v, n.Object)
+		Walk(v, n.Index)
+	case *CallExpression:
+		Walk(v, n.Callee)
+		for _, a := range n.Arguments {
+			Walk(v, a)
+		}
+	case *UnaryExpression:
+		Walk(v, n.Operand)
+	case *BinaryExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *ConditionalExpression:
+		Walk(v, n.Test)
+		Walk(v, n.Consequent)
+		Walk(v, n.Alternate)
+	case *ArrowFunction:
+		Walk(v, n.Body)
+	}
+}
+
+func walkList(v Visitor, nodes []Node) {
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+}
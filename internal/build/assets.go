@@ -0,0 +1,250 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+// assetManifestName is written to the root of the build output and maps
+// every source asset to the fingerprinted URL it is served from.
+const assetManifestName = "asset-manifest.json"
+
+// cssURLPattern matches url(...) references in stylesheets.
+var cssURLPattern = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+
+// assetFile is an asset as it will be written to the output.
+type assetFile struct {
+	source  string // absolute path of the source file
+	rel     string // project-relative path of the source, e.g. assets/logo.png
+	output  string // path relative to the output directory, with the hash
+	url     string
+	content []byte
+}
+
+// assetPipeline fingerprints the files in the assets directory. Every file
+// is renamed to include a hash of its content so that it can be cached
+// forever, and references to the original names are rewritten. Images are
+// also resized to the configured widths for use in srcset.
+type assetPipeline struct {
+	ctx      *core.JawtContext
+	files    map[string]*assetFile // by project-relative path, e.g. assets/logo.png
+	images   map[string]*imageInfo // by project-relative path of the original
+	variants []*assetFile          // resized images
+}
+
+func newAssetPipeline(ctx *core.JawtContext) *assetPipeline {
+	return &assetPipeline{
+		ctx:    ctx,
+		files:  make(map[string]*assetFile),
+		images: make(map[string]*imageInfo),
+	}
+}
+
+// Process reads and fingerprints every asset. Stylesheets are processed
+// last because the URLs they reference are part of their content.
+func (ap *assetPipeline) Process() error {
+	var sources []string
+	err := filepath.Walk(ap.ctx.Paths.AssetsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			sources = append(sources, path)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read assets: %w", err)
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		return !isStylesheet(sources[i]) && isStylesheet(sources[j])
+	})
+
+	for _, source := range sources {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return fmt.Errorf("failed to read asset %s: %w", source, err)
+		}
+		if isStylesheet(source) {
+			content = ap.rewriteCSS(source, content)
+		}
+		f := ap.add(source, content)
+
+		if isImage(source) {
+			if err := ap.processImage(f); err != nil {
+				return err
+			}
+		}
+	}
+
+	ap.ctx.Logger.Info("Assets fingerprinted",
+		core.IntField("count", len(ap.files)),
+		core.IntField("image_variants", len(ap.variants)))
+
+	return nil
+}
+
+func (ap *assetPipeline) add(source string, content []byte) *assetFile {
+	rel := filepath.ToSlash(ap.ctx.Paths.GetRelativePath(source))
+	f := ap.fingerprint(source, rel, content)
+	ap.files[rel] = f
+	return f
+}
+
+// fingerprint names content after rel with a hash of the content inserted
+// before the extension.
+func (ap *assetPipeline) fingerprint(source, rel string, content []byte) *assetFile {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:10]
+
+	ext := path.Ext(rel)
+	output := strings.TrimSuffix(rel, ext) + "." + hash + ext
+
+	return &assetFile{
+		source:  source,
+		rel:     rel,
+		output:  output,
+		url:     "/" + output,
+		content: content,
+	}
+}
+
+// Lookup returns the fingerprinted URL for a reference to an asset. The
+// reference is relative to the project root, with or without a leading slash.
+func (ap *assetPipeline) Lookup(ref string) (string, bool) {
+	if f, ok := ap.lookupFile(ref); ok {
+		return f.url, true
+	}
+	return "", false
+}
+
+func (ap *assetPipeline) lookupFile(ref string) (*assetFile, bool) {
+	ref = strings.TrimPrefix(strings.TrimPrefix(ref, "./"), "/")
+	f, ok := ap.files[ref]
+	return f, ok
+}
+
+// rewriteCSS replaces url() references to assets with their fingerprinted URLs.
+func (ap *assetPipeline) rewriteCSS(source string, content []byte) []byte {
+	dir := filepath.Dir(source)
+
+	return cssURLPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		groups := cssURLPattern.FindSubmatch(match)
+		ref := string(groups[2])
+		if isExternalURL(ref) {
+			return match
+		}
+
+		// Drop any query or fragment before resolving the file.
+		clean := ref
+		suffix := ""
+		if i := strings.IndexAny(clean, "?#"); i >= 0 {
+			clean, suffix = clean[:i], clean[i:]
+		}
+
+		var target string
+		if strings.HasPrefix(clean, "/") {
+			target = filepath.Join(ap.ctx.Paths.ProjectRoot, filepath.FromSlash(clean))
+		} else {
+			target = filepath.Join(dir, filepath.FromSlash(clean))
+		}
+
+		url, ok := ap.Lookup(filepath.ToSlash(ap.ctx.Paths.GetRelativePath(target)))
+		if !ok {
+			ap.ctx.Logger.Warn("Stylesheet references a missing asset",
+				core.StringField("stylesheet", ap.ctx.Paths.GetRelativePath(source)),
+				core.StringField("reference", ref))
+			return match
+		}
+		return []byte(`url("` + url + suffix + `")`)
+	})
+}
+
+// RewriteReferences replaces string literals in a document that name an
+// asset, such as src: "assets/logo.png", with the fingerprinted URL.
+func (ap *assetPipeline) RewriteReferences(doc *ast.Document) {
+	ast.Walk(&assetRewriter{assets: ap}, doc)
+}
+
+type assetRewriter struct {
+	ast.BaseVisitor
+	assets *assetPipeline
+}
+
+// VisitElement runs before the element's properties are visited, while src
+// still holds the original path of the image.
+func (r *assetRewriter) VisitElement(n *ast.Element) {
+	if emitter.IsImageElement(n.Name) {
+		r.assets.addImageAttributes(n)
+	}
+}
+
+func (r *assetRewriter) VisitStringLiteral(n *ast.StringLiteral) {
+	if url, ok := r.assets.Lookup(n.Value); ok {
+		n.Value = url
+	}
+}
+
+// Write copies the fingerprinted assets and image variants into outDir and
+// writes the manifest.
+func (ap *assetPipeline) Write(outDir string) error {
+	files := make([]*assetFile, 0, len(ap.files)+len(ap.variants))
+	for _, f := range ap.files {
+		files = append(files, f)
+	}
+	files = append(files, ap.variants...)
+
+	for _, f := range files {
+		target := filepath.Join(outDir, filepath.FromSlash(f.output))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create asset directory: %w", err)
+		}
+		if err := os.WriteFile(target, f.content, 0644); err != nil {
+			return fmt.Errorf("failed to write asset %s: %w", f.output, err)
+		}
+	}
+
+	manifest := make(map[string]string, len(files))
+	for _, f := range files {
+		manifest[f.rel] = f.url
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode asset manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, assetManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write asset manifest: %w", err)
+	}
+
+	return nil
+}
+
+func isStylesheet(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".css")
+}
+
+func isExternalURL(ref string) bool {
+	lower := strings.ToLower(ref)
+	return strings.HasPrefix(lower, "data:") ||
+		strings.HasPrefix(lower, "http:") ||
+		strings.HasPrefix(lower, "https:") ||
+		strings.HasPrefix(lower, "//") ||
+		strings.HasPrefix(lower, "#")
+}
@@ -3,12 +3,14 @@ package build
 import (
 	"fmt"
 	"github.com/fsnotify/fsnotify"
+	"github.com/yasufadhili/jawt/internal/ast"
 	"github.com/yasufadhili/jawt/internal/compiler"
 	"github.com/yasufadhili/jawt/internal/core"
 	"github.com/yasufadhili/jawt/internal/diagnostic"
 	"github.com/yasufadhili/jawt/internal/emitter"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +21,7 @@ type DocumentType int
 const (
 	DocumentTypePage DocumentType = iota
 	DocumentTypeComponent
+	DocumentTypeScript // a script imported by a document; only in the graph
 )
 
 type DocumentInfo struct {
@@ -43,16 +46,40 @@ type PageInfo struct {
 	Route string
 }
 
+// parsedDocument is a document that has been parsed and checked, with the
+// diagnostics found doing so, and is waiting to be emitted.
+type parsedDocument struct {
+	document *ast.Document
+	reporter *diagnostic.Reporter
+}
+
 type BuildSystem struct {
 	ctx        *core.JawtContext
 	mu         sync.RWMutex
 	docs       map[string]*DocumentInfo
 	pages      map[string]*PageInfo
 	comps      map[string]*ComponentInfo
+	asts       map[string]*ast.Document
+	classes    map[string][]string
+	parsed     map[string]*parsedDocument
+	imports    map[string][]*ast.Import // resolved imports in the graph, by document
+	cycles     map[string][]importCycle // imports that would close a cycle, by importing document
+	assets     *assetPipeline           // set for production builds only
+	chunks     *chunkPlan               // set once a production build is split
 	discoverer ProjectDiscoverer
 	compiler   *CompilerRunner
+	resolver   *ImportResolver
 	watcher    FileWatcher
 	depGraph   DependencyGraph
+
+	// tailwindCurrent is set once Tailwind has run in this process. After
+	// that it only runs again when the class manifest changes.
+	tailwindCurrent bool
+
+	// eventMu serialises file event handling, including renames that are
+	// resolved later by a timer.
+	eventMu       sync.Mutex
+	pendingRename *pendingRename
 }
 
 type FileWatcher interface {
@@ -67,9 +94,15 @@ func NewBuildSystem(ctx *core.JawtContext, watcher FileWatcher) *BuildSystem {
 		docs:       make(map[string]*DocumentInfo),
 		pages:      make(map[string]*PageInfo),
 		comps:      make(map[string]*ComponentInfo),
+		asts:       make(map[string]*ast.Document),
+		classes:    make(map[string][]string),
+		parsed:     make(map[string]*parsedDocument),
+		imports:    make(map[string][]*ast.Import),
+		cycles:     make(map[string][]importCycle),
 		discoverer: NewProjectDiscoverer(ctx),
 		watcher:    watcher,
 		compiler:   NewCompilerRunner(ctx),
+		resolver:   NewImportResolver(ctx.Paths.ProjectRoot),
 		depGraph:   NewDependencyGraph(),
 	}
 }
@@ -78,6 +111,18 @@ func NewBuildSystem(ctx *core.JawtContext, watcher FileWatcher) *BuildSystem {
 func (bs *BuildSystem) Initialise() error {
 	bs.ctx.Logger.Info("Initialising build system")
 
+	if err := bs.Build(); err != nil {
+		return err
+	}
+
+	bs.SetupWatcher()
+
+	return nil
+}
+
+// Build prepares the workspace, discovers the project and compiles every
+// document once.
+func (bs *BuildSystem) Build() error {
 	if err := bs.generateWorkspaceConfigs(); err != nil {
 		return fmt.Errorf("failed to generate workspace configs: %w", err)
 	}
@@ -90,11 +135,16 @@ func (bs *BuildSystem) Initialise() error {
 		return err
 	}
 
+	if reporter := bs.checkEnvReferences(); reporter.HasErrors() {
+		diagnostic.NewPrinter().Print(reporter)
+		return fmt.Errorf("client code refers to environment variables that are not public")
+	}
+
 	if err := bs.CompileAll(); err != nil {
 		return err
 	}
 
-	bs.SetupWatcher()
+	bs.lintUnused()
 
 	return nil
 }
@@ -136,11 +186,11 @@ func (bs *BuildSystem) DiscoverProject() error {
 		return fmt.Errorf("invalid dependency graph: %w", err)
 	}
 
-	if bs.depGraph.HasCycle() {
-		cycles := bs.depGraph.GetCycles()
-		bs.ctx.Logger.Warn("Circular dependencies detected",
-			core.IntField("cycle_count", len(cycles)))
-		// Could return error or just warn depending on requirements
+	// Imports that would close a cycle are left out of the graph, and each
+	// import along the cycle is reported when its document is compiled
+	if n := bs.cycleCount(); n > 0 {
+		bs.ctx.Logger.Error("Circular imports detected",
+			core.IntField("cycle_count", n))
 	}
 
 	bs.ctx.Logger.Info("Project discovery completed",
@@ -153,8 +203,15 @@ func (bs *BuildSystem) DiscoverProject() error {
 func (bs *BuildSystem) buildDependencyGraph() error {
 	bs.ctx.Logger.Info("Building dependency graph")
 
-	for path, doc := range bs.docs {
-		dependencies, err := bs.extractDependencies(doc)
+	// Sorted, so that the same import is reported for a cycle every time
+	paths := make([]string, 0, len(bs.docs))
+	for path := range bs.docs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		dependencies, err := bs.extractDependencies(bs.docs[path])
 		if err != nil {
 			bs.ctx.Logger.Error("Failed to extract dependencies",
 				core.StringField("path", path),
@@ -162,26 +219,66 @@ func (bs *BuildSystem) buildDependencyGraph() error {
 			continue
 		}
 
-		for _, dep := range dependencies {
-			if err := bs.depGraph.AddDependency(path, dep); err != nil {
-				bs.ctx.Logger.Error("Failed to add dependency to graph",
-					core.StringField("from", path),
-					core.StringField("to", dep),
-					core.ErrorField(err))
-			}
-		}
+		bs.setDependencies(path, dependencies)
 	}
 
 	return nil
 }
 
-func (bs *BuildSystem) extractDependencies(doc *DocumentInfo) ([]string, error) {
-	content, err := os.ReadFile(doc.AbsPath)
+// extractDependencies parses a document and returns its component and
+// script imports, resolved. The parsed document is kept for emitDocument,
+// so it isn't parsed again; its errors, and the imports that can't be
+// resolved, are reported when it is emitted. Lazy imports are not
+// dependencies.
+func (bs *BuildSystem) extractDependencies(doc *DocumentInfo) ([]*ast.Import, error) {
+	parsed, err := bs.parseDocument(doc.AbsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bs.mu.Lock()
+	bs.parsed[doc.AbsPath] = parsed
+	bs.mu.Unlock()
+
+	var deps []*ast.Import
+	for _, imp := range ExtractDependencies(parsed.document) {
+		if imp.Lazy {
+			continue
+		}
+		resolved, ok := bs.resolver.Resolve(imp, doc.AbsPath)
+		if !ok {
+			continue
+		}
+		imp.ResolvedPath = resolved
+		deps = append(deps, imp)
+	}
+
+	return deps, nil
+}
+
+// parseDocument parses and checks the JML document at path.
+func (bs *BuildSystem) parseDocument(path string) (*parsedDocument, error) {
+	reporter := diagnostic.NewReporter()
+	document, err := compiler.NewCompiler(bs.ctx).Compile(path, reporter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", doc.AbsPath, err)
+		return nil, err
 	}
+	return &parsedDocument{document: document, reporter: reporter}, nil
+}
+
+// takeParsed returns the document kept for path by extractDependencies and
+// forgets it, since emitting changes the AST. If none is kept, the document
+// is parsed.
+func (bs *BuildSystem) takeParsed(path string) (*parsedDocument, error) {
+	bs.mu.Lock()
+	parsed, ok := bs.parsed[path]
+	delete(bs.parsed, path)
+	bs.mu.Unlock()
 
-	return ExtractDependencies(string(content)), nil
+	if ok {
+		return parsed, nil
+	}
+	return bs.parseDocument(path)
 }
 
 func (bs *BuildSystem) SetupWatcher() {
@@ -195,25 +292,17 @@ func (bs *BuildSystem) SetupWatcher() {
 func (bs *BuildSystem) CompileAll() error {
 	bs.ctx.Logger.Info("Compiling all documents")
 
-	compilationOrder, err := bs.depGraph.GetCompilationOrder()
+	levels, err := bs.depGraph.GetCompilationLevels()
 	if err != nil {
 		return fmt.Errorf("failed to determine compilation order: %w", err)
 	}
 
 	bs.ctx.Logger.Info("Compilation order determined",
-		core.IntField("document_count", len(compilationOrder)))
-
-	// Compile in dependency order
-	for _, path := range compilationOrder {
-		if err := bs.CompileDocument(path); err != nil {
-			bs.ctx.Logger.Error("Failed to compile document",
-				core.StringField("path", path),
-				core.ErrorField(err))
-			return err
-		}
-	}
+		core.IntField("document_count", len(bs.depGraph.GetAllNodes())),
+		core.IntField("levels", len(levels)))
 
-	return nil
+	// Compile in dependency order, each level in parallel
+	return bs.compileLevels(levels)
 }
 
 func (bs *BuildSystem) HandleFileEvent(event fsnotify.Event) {
@@ -221,14 +310,31 @@ func (bs *BuildSystem) HandleFileEvent(event fsnotify.Event) {
 		core.StringField("operation", event.Op.String()),
 		core.StringField("file", event.Name))
 
-	if !bs.isJMLFile(event.Name) {
+	// A removed file can't be checked on disk, so only its name is checked
+	gone := event.Op&(fsnotify.Remove|fsnotify.Rename) != 0
+	if bs.isScriptPath(event.Name) {
+		bs.eventMu.Lock()
+		defer bs.eventMu.Unlock()
+		bs.HandleScriptChanged(event.Name)
+		return
+	}
+	if gone && !isJMLPath(event.Name) || !gone && !bs.isJMLFile(event.Name) {
 		bs.ctx.Logger.Debug("Ignoring non-JML file event",
 			core.StringField("file", event.Name))
 		return
 	}
 
+	bs.eventMu.Lock()
+	defer bs.eventMu.Unlock()
+
 	switch {
 	case event.Op&fsnotify.Create == fsnotify.Create:
+		// fsnotify reports a move as a Rename of the old name followed by a
+		// Create of the new one
+		if oldPath, ok := bs.takePendingRename(); ok {
+			bs.HandleFileMoved(oldPath, event.Name)
+			return
+		}
 		bs.HandleFileCreated(event.Name)
 	case event.Op&fsnotify.Write == fsnotify.Write:
 		bs.HandleFileModified(event.Name)
@@ -239,18 +345,72 @@ func (bs *BuildSystem) HandleFileEvent(event fsnotify.Event) {
 	}
 }
 
+// HandleScriptChanged copies a script that was created, modified or removed
+// into the workspace and recompiles the documents that import it, directly
+// or through other documents. If a script is gone, the imports of it are
+// dropped from the graph and reported when their documents are recompiled.
+func (bs *BuildSystem) HandleScriptChanged(path string) {
+	bs.ctx.Logger.Info("Script changed", core.StringField("path", path))
+
+	if err := bs.syncUserScript(path); err != nil {
+		bs.ctx.Logger.Error("Failed to copy script to the workspace",
+			core.StringField("path", path),
+			core.ErrorField(err))
+		return
+	}
+
+	dependents := bs.depGraph.GetTransitiveDependents(path)
+	if _, err := os.Stat(path); err != nil {
+		for _, importer := range bs.depGraph.GetDependents(path) {
+			doc, ok := bs.GetDocumentInfo(importer)
+			if !ok {
+				continue
+			}
+			deps, err := bs.extractDependencies(doc)
+			if err != nil {
+				continue
+			}
+			bs.setDependencies(importer, deps)
+		}
+	}
+
+	if len(dependents) == 0 {
+		// Nothing to recompile, but the script itself still needs tsc
+		if err := bs.runExternalCompilers(); err != nil {
+			bs.ctx.Logger.Error("Failed to compile script",
+				core.StringField("path", path),
+				core.ErrorField(err))
+		}
+		return
+	}
+	bs.recompile(dependents)
+}
+
 func (bs *BuildSystem) HandleFileCreated(path string) {
 	bs.ctx.Logger.Info("JML file created", core.StringField("path", path))
 
-	// Create DocumentInfo for the new file
-	docInfo, err := bs.discoverer.CreateDocumentInfo(path, bs.ctx.Paths.ProjectRoot)
-	if err != nil {
+	if _, err := bs.addDocumentWithDependencies(path); err != nil {
 		bs.ctx.Logger.Error("Failed to create document info for new file",
 			core.StringField("path", path),
 			core.ErrorField(err))
 		return
 	}
 
+	if err := bs.CompileDocument(path); err != nil {
+		bs.ctx.Logger.Error("Failed to compile new file",
+			core.StringField("path", path),
+			core.ErrorField(err))
+	}
+}
+
+// addDocumentWithDependencies adds the document at path to the build system
+// and its imports to the dependency graph.
+func (bs *BuildSystem) addDocumentWithDependencies(path string) (*DocumentInfo, error) {
+	docInfo, err := bs.discoverer.CreateDocumentInfo(path, bs.ctx.Paths.ProjectRoot)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add to the build system (includes adding to dependency graph)
 	bs.AddDocument(docInfo)
 
@@ -260,30 +420,16 @@ func (bs *BuildSystem) HandleFileCreated(path string) {
 		bs.ctx.Logger.Error("Failed to extract dependencies for new file",
 			core.StringField("path", path),
 			core.ErrorField(err))
-	} else {
-		for _, dep := range dependencies {
-			if err := bs.depGraph.AddDependency(path, dep); err != nil {
-				bs.ctx.Logger.Error("Failed to add dependency to graph",
-					core.StringField("from", path),
-					core.StringField("to", dep),
-					core.ErrorField(err))
-			}
-		}
+		return docInfo, nil
 	}
+	bs.setDependencies(path, dependencies)
 
-	if err := bs.CompileDocument(path); err != nil {
-		bs.ctx.Logger.Error("Failed to compile new file",
-			core.StringField("path", path),
-			core.ErrorField(err))
-	}
+	return docInfo, nil
 }
 
 func (bs *BuildSystem) HandleFileModified(path string) {
 	bs.ctx.Logger.Info("JML file modified", core.StringField("path", path))
 
-	// Get old dependencies before updating
-	oldDeps := bs.depGraph.GetDependencies(path)
-
 	// Re-parse and update document info
 	docInfo, err := bs.discoverer.CreateDocumentInfo(path, bs.ctx.Paths.ProjectRoot)
 	if err != nil {
@@ -298,22 +444,31 @@ func (bs *BuildSystem) HandleFileModified(path string) {
 		bs.ctx.Logger.Error("Failed to extract new dependencies",
 			core.StringField("path", path),
 			core.ErrorField(err))
-		newDeps = []string{}
+		newDeps = nil
 	}
 
-	bs.updateDependenciesInGraph(path, oldDeps, newDeps)
+	bs.setDependencies(path, newDeps)
 
 	bs.AddDocument(docInfo)
 
-	if err := bs.CompileDocument(path); err != nil {
-		bs.ctx.Logger.Error("Failed to recompile modified file",
+	// The file and everything that uses it, directly or not, form one
+	// change set, so the external compilers run once for all of them.
+	// Documents whose imports were cyclic before this change are retried.
+	dependents := bs.depGraph.GetTransitiveDependents(path)
+	changed := append([]string{path}, dependents...)
+	changed = append(changed, bs.retryCycles(path)...)
+	levels, err := bs.depGraph.GetSubgraphLevels(changed)
+	if err != nil {
+		bs.ctx.Logger.Error("Failed to get compilation order for dependents",
 			core.StringField("path", path),
 			core.ErrorField(err))
+		return
 	}
 
-	if err := bs.RecompileDependents(path); err != nil {
-		bs.ctx.Logger.Error("Failed to recompile dependents",
+	if err := bs.compileLevels(levels); err != nil {
+		bs.ctx.Logger.Error("Failed to recompile modified file",
 			core.StringField("path", path),
+			core.IntField("dependent_count", len(dependents)),
 			core.ErrorField(err))
 	}
 }
@@ -328,30 +483,98 @@ func (bs *BuildSystem) HandleFileDeleted(path string) {
 		return
 	}
 
-	// Remove from the build system
-	bs.RemoveDocument(path)
+	// The importers are only known while the document is in the graph
+	importers := bs.depGraph.GetDependents(path)
 
-	// TODO: Update dependencies in other documents that might reference this file
-	// TODO: Recompile dependent documents if necessary
+	bs.RemoveDocument(path)
+	bs.removeArtifacts(path)
 
 	bs.ctx.Logger.Info("Successfully removed deleted file from build system",
-		core.StringField("path", path))
+		core.StringField("path", path),
+		core.IntField("importer_count", len(importers)))
+
+	// Recompiling the importers reports their now unresolved imports
+	bs.recompile(append(importers, bs.retryCycles(path)...))
 }
 
-// HandleFileRenamed handles a file being renamed
+// HandleFileRenamed handles a Rename event, which fsnotify sends for the
+// old name of a file. If a Create for the new name follows shortly, the two
+// are handled together as a move; otherwise the file is treated as deleted.
 func (bs *BuildSystem) HandleFileRenamed(path string) {
 	bs.ctx.Logger.Info("JML file renamed", core.StringField("path", path))
 
-	// For fsnotify, rename events are a bit tricky
-	// handle this as a potential delete followed by a create
-	// The actual implementation depends on whether the file still exists
-
 	if _, err := os.Stat(path); err == nil {
-		// File exists, treat as created/modified
+		// Still there, e.g. an editor saving through a temporary file
 		bs.HandleFileModified(path)
-	} else {
-		// File doesn't exist, treat as deleted
-		bs.HandleFileDeleted(path)
+		return
+	}
+
+	if oldPath, ok := bs.takePendingRename(); ok {
+		bs.HandleFileDeleted(oldPath)
+	}
+
+	pending := &pendingRename{path: path}
+	pending.timer = time.AfterFunc(renamePairWindow, func() {
+		bs.eventMu.Lock()
+		defer bs.eventMu.Unlock()
+
+		if bs.pendingRename == pending {
+			bs.pendingRename = nil
+			bs.HandleFileDeleted(path)
+		}
+	})
+	bs.pendingRename = pending
+}
+
+// HandleFileMoved moves a document from oldPath to newPath. The old outputs
+// are removed, the document is compiled at its new location, and its
+// importers are recompiled since their imports no longer point at it.
+func (bs *BuildSystem) HandleFileMoved(oldPath, newPath string) {
+	if oldPath == newPath {
+		bs.HandleFileModified(newPath)
+		return
+	}
+
+	bs.ctx.Logger.Info("JML file moved",
+		core.StringField("from", oldPath),
+		core.StringField("to", newPath))
+
+	importers := bs.depGraph.GetDependents(oldPath)
+
+	bs.RemoveDocument(oldPath)
+	bs.removeArtifacts(oldPath)
+
+	if _, err := bs.addDocumentWithDependencies(newPath); err != nil {
+		bs.ctx.Logger.Error("Failed to create document info for moved file",
+			core.StringField("path", newPath),
+			core.ErrorField(err))
+		bs.recompile(importers)
+		return
+	}
+
+	bs.recompile(append(append(importers, newPath), bs.retryCycles(newPath)...))
+}
+
+// recompile compiles the given documents as one change set, in dependency
+// order. With no documents, only the stylesheet is brought up to date.
+func (bs *BuildSystem) recompile(paths []string) {
+	if len(paths) == 0 {
+		if err := bs.updateStyles(); err != nil {
+			bs.ctx.Logger.Error("Failed to update styles", core.ErrorField(err))
+		}
+		return
+	}
+
+	levels, err := bs.depGraph.GetSubgraphLevels(paths)
+	if err != nil {
+		bs.ctx.Logger.Error("Failed to get compilation order", core.ErrorField(err))
+		return
+	}
+
+	if err := bs.compileLevels(levels); err != nil {
+		bs.ctx.Logger.Error("Failed to recompile documents",
+			core.IntField("document_count", len(paths)),
+			core.ErrorField(err))
 	}
 }
 
@@ -372,7 +595,7 @@ func (bs *BuildSystem) AddDocument(doc *DocumentInfo) {
 	// Add to type-specific maps
 	switch doc.Type {
 	case DocumentTypePage:
-		bs.pages[doc.AbsPath] = &PageInfo{DocumentInfo: *doc}
+		bs.pages[doc.AbsPath] = &PageInfo{DocumentInfo: *doc, Route: bs.routeForPage(doc.AbsPath)}
 	case DocumentTypeComponent:
 		bs.comps[doc.AbsPath] = &ComponentInfo{DocumentInfo: *doc}
 	}
@@ -400,123 +623,157 @@ func (bs *BuildSystem) RemoveDocument(path string) {
 
 		// Remove from main document map
 		delete(bs.docs, path)
+		delete(bs.parsed, path)
+		delete(bs.asts, path)
+		delete(bs.classes, path)
+		delete(bs.imports, path)
+		delete(bs.cycles, path)
 
+		deps := bs.depGraph.GetDependencies(path)
 		if err := bs.depGraph.RemoveNode(path); err != nil {
 			bs.ctx.Logger.Error("Failed to remove document from dependency graph",
 				core.StringField("path", path),
 				core.ErrorField(err))
 		}
+		bs.pruneScripts(deps)
 	}
 }
 
+// pruneScripts removes the scripts among paths that no document imports
+// any more from the dependency graph.
+func (bs *BuildSystem) pruneScripts(paths []string) {
+	for _, path := range paths {
+		if !isJMLPath(path) && len(bs.depGraph.GetDependents(path)) == 0 {
+			_ = bs.depGraph.RemoveNode(path)
+		}
+	}
+}
+
+// CompileDocument compiles a single document and runs the external compilers.
 func (bs *BuildSystem) CompileDocument(path string) error {
+	return bs.CompileDocuments([]string{path})
+}
+
+// CompileDocuments compiles a change set, given in dependency order. Every
+// document is compiled to TypeScript first, then tsc and Tailwind run once
+// for the whole set rather than once per document.
+func (bs *BuildSystem) CompileDocuments(paths []string) error {
+	levels := make([][]string, len(paths))
+	for i, path := range paths {
+		levels[i] = []string{path}
+	}
+	return bs.compileLevels(levels)
+}
+
+// emitDocument compiles a JML document and emits its TypeScript module.
+// Diagnostics are added to reporter rather than printed, so that callers
+// compiling several documents at once can print them in a stable order. It
+// returns nil if the document is not part of the project.
+func (bs *BuildSystem) emitDocument(path string, reporter *diagnostic.Reporter) (*DocumentInfo, error) {
 	bs.mu.RLock()
 	doc, exists := bs.docs[path]
 	bs.mu.RUnlock()
 
 	if !exists {
-		return nil // Document doesn't exist, nothing to compile
+		return nil, nil // Document doesn't exist, nothing to compile
 	}
 
 	// 1. Compile JML to TypeScript
-	reporter := diagnostic.NewReporter()
-	jmlCompiler := compiler.NewCompiler(bs.ctx)
-	ast, err := jmlCompiler.Compile(doc.AbsPath, reporter)
+	parsed, err := bs.takeParsed(doc.AbsPath)
 	if err != nil {
-		return fmt.Errorf("failed to compile JML file %s: %w", doc.AbsPath, err)
+		return nil, fmt.Errorf("failed to compile JML file %s: %w", doc.AbsPath, err)
+	}
+	document := parsed.document
+	for _, d := range parsed.reporter.All() {
+		reporter.Add(d)
+	}
+	for _, d := range bs.cycleDiagnostics(path) {
+		reporter.Add(d)
+	}
+	if reporter.HasErrors() {
+		return nil, fmt.Errorf("compilation of %s failed with errors", doc.AbsPath)
 	}
+
+	bs.resolver.ResolveDocument(document, reporter)
 	if reporter.HasErrors() {
-		printer := diagnostic.NewPrinter()
-		printer.Print(reporter)
-		return fmt.Errorf("compilation of %s failed with errors", doc.AbsPath)
+		return nil, fmt.Errorf("compilation of %s failed with errors", doc.AbsPath)
+	}
+	if bs.assets != nil {
+		bs.assets.RewriteReferences(document)
 	}
+	bs.setClasses(doc.AbsPath, compiler.ExtractClasses(document))
+
+	bs.mu.Lock()
+	bs.asts[doc.AbsPath] = document
+	bs.mu.Unlock()
 
 	// 2. Emit TypeScript from the AST to the .jawt/src/user directory
 	emitter := emitter.NewEmitter(bs.ctx)
-	if err := emitter.Emit(ast); err != nil {
-		return fmt.Errorf("failed to emit TypeScript for %s: %w", doc.AbsPath, err)
+	if err := emitter.Emit(document); err != nil {
+		return nil, fmt.Errorf("failed to emit TypeScript for %s: %w", doc.AbsPath, err)
 	}
 
-	// 3. Run external compilers
+	return doc, nil
+}
+
+// runExternalCompilers type-checks and compiles the emitted TypeScript and
+// regenerates the Tailwind stylesheet if the classes in use have changed.
+func (bs *BuildSystem) runExternalCompilers() error {
 	if err := bs.compiler.RunTSC(); err != nil {
 		return fmt.Errorf("failed to run tsc: %w", err)
 	}
 
-	if err := bs.compiler.RunTailwind(); err != nil {
-		return fmt.Errorf("failed to run tailwind: %w", err)
+	return bs.updateStyles()
+}
+
+// updateStyles rewrites the class manifest and runs Tailwind if it changed.
+func (bs *BuildSystem) updateStyles() error {
+	changed, err := bs.writeClassManifest()
+	if err != nil {
+		return err
 	}
 
-	bs.mu.Lock()
-	doc.IsCompiled = true
-	bs.mu.Unlock()
+	if bs.ctx.BuildOptions.UsesTailwindCSS && (changed || !bs.tailwindCurrent) {
+		if err := bs.compiler.RunTailwind(); err != nil {
+			return fmt.Errorf("failed to run tailwind: %w", err)
+		}
+		bs.tailwindCurrent = true
+	}
 
 	return nil
 }
 
-// RecompileDependents recompiles all documents that depend on the given document
+// RecompileDependents recompiles all documents that depend on the given
+// document, directly or through other documents
 func (bs *BuildSystem) RecompileDependents(path string) error {
-	dependents := bs.depGraph.GetDependents(path)
+	dependents := bs.depGraph.GetTransitiveDependents(path)
 
 	bs.ctx.Logger.Info("Recompiling dependents",
 		core.StringField("changed_file", path),
 		core.IntField("dependent_count", len(dependents)))
 
-	// Get compilation order for just the dependents
-	compilationOrder, err := bs.getCompilationOrderForNodes(dependents)
+	// Order the dependents among themselves; the changed document is
+	// already compiled
+	levels, err := bs.depGraph.GetSubgraphLevels(dependents)
 	if err != nil {
 		return fmt.Errorf("failed to get compilation order for dependents: %w", err)
 	}
 
-	// Recompile in dependency order
-	for _, depPath := range compilationOrder {
-		if err := bs.CompileDocument(depPath); err != nil {
-			return fmt.Errorf("failed to recompile dependent %s: %w", depPath, err)
-		}
+	if err := bs.compileLevels(levels); err != nil {
+		return fmt.Errorf("failed to recompile dependents of %s: %w", path, err)
 	}
 
 	return nil
 }
 
-func (bs *BuildSystem) getCompilationOrderForNodes(nodes []string) ([]string, error) {
-	// This would create a subgraph with just the specified nodes
-	// and their dependencies, then return topological order
-
-	// Placeholder implementation
-	return nodes, nil
-}
-
-func (bs *BuildSystem) updateDependenciesInGraph(path string, oldDeps, newDeps []string) {
-	// Remove old dependencies that are no longer present
-	for _, oldDep := range oldDeps {
-		found := false
-		for _, newDep := range newDeps {
-			if oldDep == newDep {
-				found = true
-				break
-			}
-		}
-		if !found {
-			bs.depGraph.RemoveDependency(path, oldDep)
-		}
-	}
-
-	// Add new dependencies
-	for _, newDep := range newDeps {
-		found := false
-		for _, oldDep := range oldDeps {
-			if newDep == oldDep {
-				found = true
-				break
-			}
-		}
-		if !found {
-			bs.depGraph.AddDependency(path, newDep)
-		}
-	}
+// isJMLPath reports whether a path names a JML file, without checking that
+// it exists.
+func isJMLPath(filePath string) bool {
+	return strings.HasSuffix(strings.ToLower(filePath), ".jml")
 }
 
 func (bs *BuildSystem) isJMLFile(filePath string) bool {
-	if !strings.HasSuffix(strings.ToLower(filePath), ".jml") {
+	if !isJMLPath(filePath) {
 		return false
 	}
 
@@ -527,6 +784,17 @@ func (bs *BuildSystem) isJMLFile(filePath string) bool {
 	return true
 }
 
+// isScriptPath reports whether a path names a TypeScript file in the
+// scripts directory, without checking that it exists.
+func (bs *BuildSystem) isScriptPath(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext != ".ts" && ext != ".tsx" {
+		return false
+	}
+	_, ok := within(bs.ctx.Paths.ScriptsDir, filePath)
+	return ok
+}
+
 // getDocumentTypeString returns a string representation of the document type
 func (bs *BuildSystem) getDocumentTypeString(docType DocumentType) string {
 	switch docType {
@@ -534,6 +802,8 @@ func (bs *BuildSystem) getDocumentTypeString(docType DocumentType) string {
 		return "page"
 	case DocumentTypeComponent:
 		return "component"
+	case DocumentTypeScript:
+		return "script"
 	default:
 		return "unknown"
 	}
@@ -565,6 +835,9 @@ func (bs *BuildSystem) syncWorkspaceSources() error {
 	if err := bs.extractInternalScripts(); err != nil {
 		return fmt.Errorf("failed to extract internal scripts: %w", err)
 	}
+	if err := bs.writeEnvModule(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -599,16 +872,43 @@ func (bs *BuildSystem) copyUserScripts() error {
 	})
 }
 
+// syncUserScript copies a single script into the workspace, or removes its
+// copy if the script no longer exists.
+func (bs *BuildSystem) syncUserScript(path string) error {
+	relPath, err := filepath.Rel(bs.ctx.Paths.ScriptsDir, path)
+	if err != nil {
+		return err
+	}
+	destPath := filepath.Join(bs.ctx.Paths.UserSrcDir, relPath)
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(destPath, content, 0644)
+}
+
 func (bs *BuildSystem) extractInternalScripts() error {
 	internalScripts := map[string]string{
 		"browser.ts": `// Placeholder for Jawt's internal browser API
 export function setTitle(title: string) { console.log("Setting title: " + title); }
 export function scrollToTop() { console.log('Scrolling to top'); }
 `,
-		"store.ts":   `// Placeholder for Jawt's internal store API
+		"store.ts": `// Placeholder for Jawt's internal store API
 export function get(key: string) { console.log("Getting key: " + key); return null; }
 export function set(key: string, value: any) { console.log("Setting key: " + key + " with value: " + value); }
 `,
+		routerModuleName: routerModule,
 	}
 
 	for filename, content := range internalScripts {
@@ -628,6 +928,10 @@ func (bs *BuildSystem) generateWorkspaceConfigs() error {
 	bs.ctx.Logger.Info("Generating workspace configurations")
 
 	// Create tsconfig.json
+	outDir, err := filepath.Rel(bs.ctx.Paths.JawtDir, bs.ctx.Paths.BuildDir)
+	if err != nil {
+		return fmt.Errorf("failed to locate build directory: %w", err)
+	}
 	tsconfigContent := `{
 	  "compilerOptions": {
 	    "target": "ESNext",
@@ -646,7 +950,7 @@ func (bs *BuildSystem) generateWorkspaceConfigs() error {
 	      "@jawt/*": ["src/internal/*"]
 	    },
 	    "lib": ["ESNext", "DOM"],
-	    "outDir": "../build",
+	    "outDir": "` + filepath.ToSlash(outDir) + `",
 	    "rootDir": "src"
 	  },
 	  "include": ["src/**/*.ts", "src/**/*.tsx"],
@@ -656,10 +960,15 @@ func (bs *BuildSystem) generateWorkspaceConfigs() error {
 		return fmt.Errorf("failed to write tsconfig.json: %w", err)
 	}
 
-	// Create tailwind.config.js
+	// Create tailwind.config.js. Tailwind scans the class manifest written by
+	// the compiler rather than the emitted TypeScript.
+	manifest, err := filepath.Rel(bs.ctx.Paths.JawtDir, bs.classManifestPath())
+	if err != nil {
+		return fmt.Errorf("failed to locate class manifest: %w", err)
+	}
 	tailwindConfigContent := `/** @type {import('tailwindcss').Config} */
 	module.exports = {
-	  content: ["./src/user/**/*.ts", "./src/internal/**/*.ts"],
+	  content: ["./` + filepath.ToSlash(manifest) + `"],
 	  theme: {
 	    extend: {},
 	  },
@@ -669,5 +978,9 @@ func (bs *BuildSystem) generateWorkspaceConfigs() error {
 		return fmt.Errorf("failed to write tailwind.config.js: %w", err)
 	}
 
+	if err := os.WriteFile(tailwindInputPath(bs.ctx.Paths), []byte(tailwindInput), 0644); err != nil {
+		return fmt.Errorf("failed to write tailwind input stylesheet: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,421 @@
+package build
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+// bundleReportFile is where the bundle report is written, in the .jawt
+// directory so that it isn't deployed with the site.
+const bundleReportFile = "bundle-report.json"
+
+// Size is the size of some output, as written and after gzip.
+type Size struct {
+	Raw  int64 `json:"raw"`
+	Gzip int64 `json:"gzip"`
+}
+
+func (s *Size) add(o Size) {
+	s.Raw += o.Raw
+	s.Gzip += o.Gzip
+}
+
+// BundleFile is one output file loaded by a route.
+type BundleFile struct {
+	Path   string `json:"path"`   // URL path in the output
+	Kind   string `json:"kind"`   // "js", "css" or "asset"
+	Source string `json:"source"` // document, script or package it comes from
+	Size
+}
+
+// RouteBundle is everything a route loads. Modules are listed in the order
+// they are imported, followed by stylesheets and assets.
+type RouteBundle struct {
+	Route  string       `json:"route"`
+	Page   string       `json:"page"`
+	JS     Size         `json:"js"`
+	CSS    Size         `json:"css"`
+	Assets Size         `json:"assets"`
+	Total  Size         `json:"total"`
+	Files  []BundleFile `json:"files"`
+}
+
+// BundleReport is the cost of every route of a production build.
+type BundleReport struct {
+	Routes []RouteBundle `json:"routes"`
+}
+
+// BundleReport measures what each page of the build in outDir loads: the
+// JavaScript modules reached from the page module, including the runtime
+// resolved through imports, the stylesheets, and the assets its documents
+// refer to. Each file is attributed to the document, script or package it
+// was built from.
+func (bs *BuildSystem) BundleReport(outDir string, imports map[string]string) (*BundleReport, error) {
+	bs.mu.RLock()
+	docs := make(map[string]*ast.Document, len(bs.asts))
+	for p, doc := range bs.asts {
+		docs[p] = doc
+	}
+	pages := make([]*PageInfo, 0, len(bs.pages))
+	for _, page := range bs.pages {
+		pages = append(pages, page)
+	}
+	bs.mu.RUnlock()
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Route < pages[j].Route })
+
+	m := &bundleMeasurer{
+		outDir:  outDir,
+		imports: imports,
+		sources: bs.moduleSources(),
+		sizes:   make(map[string]Size),
+	}
+	e := emitter.NewEmitter(bs.ctx)
+
+	report := &BundleReport{Routes: []RouteBundle{}}
+	for _, page := range pages {
+		if _, ok := docs[page.AbsPath]; !ok {
+			continue
+		}
+		route := RouteBundle{Route: page.Route, Page: bs.graphID(page.AbsPath), Files: []BundleFile{}}
+
+		entry := e.ModuleURL(page.AbsPath)
+		if bs.chunks != nil {
+			c, err := bs.chunks.chunkFor(page.AbsPath)
+			if err != nil {
+				return nil, err
+			}
+			entry = c.url
+		}
+		for _, url := range m.modules(entry) {
+			if err := m.addFile(&route, url, "js", ""); err != nil {
+				return nil, err
+			}
+		}
+
+		if bs.ctx.BuildOptions.UsesTailwindCSS {
+			if err := m.addFile(&route, "/tailwind.css", "css", "tailwind"); err != nil {
+				return nil, err
+			}
+		}
+
+		used, _ := reachableFromPages([]string{page.AbsPath}, docs)
+		reached := make([]string, 0, len(used))
+		for p := range used {
+			reached = append(reached, p)
+		}
+		sort.Strings(reached)
+		seen := make(map[string]bool)
+		for _, p := range reached {
+			for _, url := range assetReferences(docs[p]) {
+				if seen[url] || !m.exists(url) {
+					continue
+				}
+				seen[url] = true
+				if err := m.addFile(&route, url, "asset", bs.graphID(p)); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		route.Total.add(route.JS)
+		route.Total.add(route.CSS)
+		route.Total.add(route.Assets)
+		report.Routes = append(report.Routes, route)
+	}
+
+	return report, nil
+}
+
+// moduleSources maps the URL of every compiled document and script to its
+// project-relative source path, and every chunk to the documents in it.
+func (bs *BuildSystem) moduleSources() map[string]string {
+	e := emitter.NewEmitter(bs.ctx)
+	sources := make(map[string]string)
+
+	bs.mu.RLock()
+	for p := range bs.docs {
+		sources[e.ModuleURL(p)] = bs.graphID(p)
+	}
+	bs.mu.RUnlock()
+
+	if bs.chunks != nil {
+		for _, c := range bs.chunks.chunks {
+			names := make([]string, len(c.modules))
+			for i, m := range c.modules {
+				names[i] = bs.graphID(m)
+			}
+			sources[c.url] = strings.Join(names, ", ")
+		}
+	}
+
+	scripts, _ := bs.ctx.Paths.GetTypeScriptFiles()
+	for _, p := range scripts {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		sources[e.ModuleURL(p)] = bs.graphID(p)
+	}
+
+	return sources
+}
+
+// bundleMeasurer follows module imports in the output and measures files,
+// caching what it has read.
+type bundleMeasurer struct {
+	outDir  string
+	imports map[string]string
+	sources map[string]string
+	sizes   map[string]Size
+}
+
+func (m *bundleMeasurer) file(url string) string {
+	return filepath.Join(m.outDir, filepath.FromSlash(strings.TrimPrefix(url, "/")))
+}
+
+func (m *bundleMeasurer) exists(url string) bool {
+	if !strings.HasPrefix(url, "/") {
+		return false
+	}
+	info, err := os.Stat(m.file(url))
+	return err == nil && !info.IsDir()
+}
+
+// modules returns the URL of the module at entry and of every module it
+// imports, directly or not, in the order they are found. Dynamic imports
+// are included, since the page loads them too.
+func (m *bundleMeasurer) modules(entry string) []string {
+	return m.crawl(entry, true)
+}
+
+// staticModules is like modules but leaves out dynamic imports, which are
+// only loaded once the code that imports them runs.
+func (m *bundleMeasurer) staticModules(entry string) []string {
+	return m.crawl(entry, false)
+}
+
+func (m *bundleMeasurer) crawl(entry string, dynamic bool) []string {
+	seen := map[string]bool{entry: true}
+	order := []string{entry}
+	for i := 0; i < len(order); i++ {
+		content, err := os.ReadFile(m.file(order[i]))
+		if err != nil {
+			continue
+		}
+		for _, match := range tsImportPattern.FindAllStringSubmatch(string(content), -1) {
+			if !dynamic && strings.Contains(match[0], "(") {
+				continue
+			}
+			url := m.resolve(match[1], order[i])
+			if url == "" || seen[url] || !m.exists(url) {
+				continue
+			}
+			seen[url] = true
+			order = append(order, url)
+		}
+	}
+	return order
+}
+
+// resolve returns the URL a module specifier in the module at from refers
+// to, using the import map for bare specifiers.
+func (m *bundleMeasurer) resolve(spec, from string) string {
+	switch {
+	case strings.HasPrefix(spec, "./"), strings.HasPrefix(spec, "../"):
+		return path.Join(path.Dir(from), spec)
+	case strings.HasPrefix(spec, "/"):
+		return spec
+	}
+	if url, ok := m.imports[spec]; ok {
+		return url
+	}
+	// The longest prefix mapping wins, as in the browser
+	best := ""
+	for prefix := range m.imports {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(spec, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return m.imports[best] + strings.TrimPrefix(spec, best)
+}
+
+// source attributes a module to what it was built from.
+func (m *bundleMeasurer) source(url string) string {
+	if src, ok := m.sources[url]; ok {
+		return src
+	}
+	for _, pkg := range vendorPackages {
+		if strings.HasPrefix(url, "/"+vendorDir+"/"+pkg.name+"/") {
+			return pkg.name
+		}
+	}
+	return "jawt"
+}
+
+func (m *bundleMeasurer) addFile(route *RouteBundle, url, kind, source string) error {
+	size, ok := m.sizes[url]
+	if !ok {
+		content, err := os.ReadFile(m.file(url))
+		if err != nil {
+			return fmt.Errorf("failed to measure %s: %w", url, err)
+		}
+		size = Size{Raw: int64(len(content)), Gzip: gzipSize(content)}
+		m.sizes[url] = size
+	}
+
+	if kind == "js" {
+		source = m.source(url)
+	}
+	route.Files = append(route.Files, BundleFile{Path: url, Kind: kind, Source: source, Size: size})
+
+	switch kind {
+	case "js":
+		route.JS.add(size)
+	case "css":
+		route.CSS.add(size)
+	default:
+		route.Assets.add(size)
+	}
+	return nil
+}
+
+func gzipSize(content []byte) int64 {
+	var buf bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	w.Write(content)
+	w.Close()
+	return int64(buf.Len())
+}
+
+// assetReferences returns the string literals in doc that are URLs in the
+// output, such as the fingerprinted src of an image.
+func assetReferences(doc *ast.Document) []string {
+	c := &assetCollector{}
+	ast.Walk(c, doc)
+	return c.urls
+}
+
+type assetCollector struct {
+	ast.BaseVisitor
+	urls []string
+}
+
+func (c *assetCollector) VisitStringLiteral(n *ast.StringLiteral) {
+	if strings.HasPrefix(n.Value, "/") && !strings.HasPrefix(n.Value, "//") &&
+		!strings.HasSuffix(n.Value, ".js") && !strings.HasSuffix(n.Value, ".css") {
+		c.urls = append(c.urls, n.Value)
+	}
+}
+
+// writeBundleReport writes the report as JSON to the .jawt directory.
+func writeBundleReport(ctx *core.JawtContext, report *BundleReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle report: %w", err)
+	}
+	if err := os.MkdirAll(ctx.Paths.JawtDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", ctx.Paths.JawtDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(ctx.Paths.JawtDir, bundleReportFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle report: %w", err)
+	}
+	return nil
+}
+
+// writeBundleTable prints the size of each route as a table.
+func writeBundleTable(w io.Writer, report *BundleReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Route\tJS\tCSS\tAssets\tTotal\t")
+	for _, r := range report.Routes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", r.Route, formatSize(r.JS), formatSize(r.CSS), formatSize(r.Assets), formatSize(r.Total))
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "Sizes are raw / gzip.")
+}
+
+func formatSize(s Size) string {
+	return formatBytes(s.Raw) + " / " + formatBytes(s.Gzip)
+}
+
+func formatBytes(n int64) string {
+	if n < 1000 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f kB", float64(n)/1000)
+}
+
+// checkBudgets compares the gzip size of every route to its budget and
+// fails if any is over.
+func checkBudgets(ctx *core.JawtContext, report *BundleReport) error {
+	budgets := ctx.ProjectConfig.Budgets
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	over := 0
+	for _, r := range report.Routes {
+		budget, ok := budgetFor(budgets, r.Route)
+		if !ok {
+			continue
+		}
+		limits := []struct {
+			name  string
+			size  Size
+			limit int
+		}{
+			{"JS", r.JS, budget.JS},
+			{"CSS", r.CSS, budget.CSS},
+			{"assets", r.Assets, budget.Assets},
+			{"total", r.Total, budget.Total},
+		}
+		for _, l := range limits {
+			if l.limit > 0 && l.size.Gzip > int64(l.limit)*1000 {
+				ctx.Logger.Error("Route exceeds its performance budget",
+					core.StringField("route", r.Route),
+					core.StringField("kind", l.name),
+					core.StringField("size", formatBytes(l.size.Gzip)),
+					core.StringField("budget", fmt.Sprintf("%d kB", l.limit)))
+				over++
+			}
+		}
+	}
+
+	if over > 0 {
+		return fmt.Errorf("%d performance budgets exceeded; see %s", over, filepath.Join(ctx.Paths.JawtDir, bundleReportFile))
+	}
+	return nil
+}
+
+// budgetFor returns the budget of a route, or the "*" budget if it has
+// none of its own.
+func budgetFor(budgets []core.Budget, route string) (core.Budget, bool) {
+	var fallback *core.Budget
+	for i, b := range budgets {
+		if b.Route == route {
+			return b, true
+		}
+		if b.Route == "*" && fallback == nil {
+			fallback = &budgets[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return core.Budget{}, false
+}
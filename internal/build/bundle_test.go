@@ -0,0 +1,71 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBundleMeasurerModules(t *testing.T) {
+	out := t.TempDir()
+	files := map[string]string{
+		"user/app/index.js":                  "import './../components/card.js';\nimport { LitElement } from 'lit';\nvoid import('../components/tree.js');",
+		"user/components/card.js":            `import{classMap}from"lit/directives/class-map.js";import*as analytics from"../analytics.js";`,
+		"user/components/tree.js":            "import './tree.js';",
+		"user/analytics.js":                  "export const track = () => {};",
+		"vendor/lit/index.js":                "export * from 'lit-html';",
+		"vendor/lit/directives/class-map.js": "",
+		"vendor/lit-html/lit-html.js":        "",
+	}
+	writeTree(t, out, files)
+
+	m := &bundleMeasurer{
+		outDir: out,
+		imports: map[string]string{
+			"lit":       "/vendor/lit/index.js",
+			"lit/":      "/vendor/lit/",
+			"lit-html":  "/vendor/lit-html/lit-html.js",
+			"lit-html/": "/vendor/lit-html/",
+		},
+		sources: map[string]string{"/user/app/index.js": "app/index.jml"},
+	}
+
+	expected := []string{
+		"/user/app/index.js",
+		"/user/components/card.js",
+		"/vendor/lit/index.js",
+		"/user/components/tree.js",
+		"/vendor/lit/directives/class-map.js",
+		"/user/analytics.js",
+		"/vendor/lit-html/lit-html.js",
+	}
+	if got := m.modules("/user/app/index.js"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected modules %v, got %v", expected, got)
+	}
+
+	for url, source := range map[string]string{
+		"/user/app/index.js":                  "app/index.jml",
+		"/vendor/lit/directives/class-map.js": "lit",
+		"/vendor/lit-html/lit-html.js":        "lit-html",
+	} {
+		if got := m.source(url); got != source {
+			t.Errorf("Expected %s to come from %s, got %s", url, source, got)
+		}
+	}
+}
+
+// writeTree writes files, by slash-separated path relative to root, and the
+// directories they are in.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
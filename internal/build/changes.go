@@ -0,0 +1,58 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+// renamePairWindow is how long a Rename waits for the Create that completes
+// a move before it is treated as a deletion.
+const renamePairWindow = 250 * time.Millisecond
+
+// pendingRename is a file that has been renamed away from path and whose new
+// name is not known yet.
+type pendingRename struct {
+	path  string
+	timer *time.Timer
+}
+
+// takePendingRename returns the old path of a rename that is waiting for its
+// Create and stops waiting. It must be called with eventMu held.
+func (bs *BuildSystem) takePendingRename() (string, bool) {
+	pending := bs.pendingRename
+	if pending == nil {
+		return "", false
+	}
+	pending.timer.Stop()
+	bs.pendingRename = nil
+	return pending.path, true
+}
+
+// removeArtifacts deletes the emitted TypeScript module of a document and
+// everything tsc produced from it.
+func (bs *BuildSystem) removeArtifacts(source string) {
+	module := emitter.NewEmitter(bs.ctx).ModulePath(source)
+
+	files := []string{module}
+	if rel, err := filepath.Rel(bs.ctx.Paths.SrcDir, module); err == nil {
+		base := filepath.Join(bs.ctx.Paths.BuildDir, strings.TrimSuffix(rel, filepath.Ext(rel)))
+		files = append(files, base+".js", base+".js.map", base+".d.ts")
+	}
+
+	for _, file := range files {
+		err := os.Remove(file)
+		switch {
+		case err == nil:
+			bs.ctx.Logger.Debug("Removed stale output", core.StringField("file", file))
+		case !os.IsNotExist(err):
+			bs.ctx.Logger.Warn("Failed to remove stale output",
+				core.StringField("file", file),
+				core.ErrorField(err))
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+const (
+	chunksDir = "chunks"
+
+	// routeManifestName is written to the root of the build output and maps
+	// every route to the chunk that renders it. The router reads it to load
+	// a route's chunk before navigating to it.
+	routeManifestName = "route-manifest.json"
+)
+
+// chunk is a module of the build output that compiled documents were
+// bundled into.
+type chunk struct {
+	url     string
+	modules []string // the documents in it, sorted
+}
+
+// chunkPlan is how the documents of a production build are split up.
+type chunkPlan struct {
+	chunks  []*chunk
+	of      map[string]*chunk   // the chunk that renders each page
+	preload map[string][]string // module URLs by page
+}
+
+// routeManifestEntry describes how a route is loaded.
+type routeManifestEntry struct {
+	Page    string   `json:"page"`
+	Tag     string   `json:"tag"`
+	Chunk   string   `json:"chunk"`
+	Preload []string `json:"preload"`
+}
+
+// splitChunks bundles the compiled documents in outDir with esbuild: one
+// chunk per route, shared chunks for documents used by several routes, and
+// a chunk for each document that is imported lazily. It writes the
+// route manifest and removes the modules of the documents it bundled.
+// Scripts, builtin modules and runtime packages stay separate modules.
+func (bs *BuildSystem) splitChunks(outDir string, imports map[string]string) error {
+	e := emitter.NewEmitter(bs.ctx)
+
+	bs.mu.RLock()
+	documents := make(map[string]string, len(bs.asts)) // by module file
+	for p := range bs.asts {
+		documents[filepath.Join(outDir, filepath.FromSlash(strings.TrimPrefix(e.ModuleURL(p), "/")))] = p
+	}
+	var pages []string
+	for p := range bs.pages {
+		if _, ok := bs.asts[p]; ok {
+			pages = append(pages, p)
+		}
+	}
+	lazy := lazyDocuments(bs.asts)
+	bs.mu.RUnlock()
+	sort.Strings(pages)
+
+	// Pages and lazily imported documents start chunks of their own, named
+	// after their element
+	var entries []api.EntryPoint
+	for _, p := range append(append([]string{}, pages...), lazy...) {
+		entries = append(entries, api.EntryPoint{
+			InputPath:  filepath.Join(outDir, filepath.FromSlash(strings.TrimPrefix(e.ModuleURL(p), "/"))),
+			OutputPath: e.TagName(p),
+		})
+	}
+
+	result := api.Build(chunkBuildOptions(outDir, entries, documents, bs.ctx.BuildOptions.SourceMaps, bs.ctx.ProjectConfig.Build.ShadowDOM))
+	if len(result.Errors) > 0 {
+		msg := result.Errors[0]
+		if msg.Location != nil {
+			return fmt.Errorf("failed to bundle chunks: %s:%d: %s", msg.Location.File, msg.Location.Line, msg.Text)
+		}
+		return fmt.Errorf("failed to bundle chunks: %s", msg.Text)
+	}
+
+	plan, bundled, err := chunkPlanFromMetafile(outDir, result.Metafile, documents, pages)
+	if err != nil {
+		return err
+	}
+	for _, file := range bundled {
+		_ = os.Remove(file)
+		_ = os.Remove(file + ".map")
+	}
+
+	measurer := &bundleMeasurer{outDir: outDir, imports: imports}
+	manifest := make(map[string]routeManifestEntry, len(pages))
+	bs.mu.RLock()
+	for _, p := range pages {
+		c := plan.of[p]
+		plan.preload[p] = measurer.staticModules(c.url)
+		manifest[bs.pages[p].Route] = routeManifestEntry{
+			Page:    bs.graphID(p),
+			Tag:     e.TagName(p),
+			Chunk:   c.url,
+			Preload: plan.preload[p],
+		}
+	}
+	bs.mu.RUnlock()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode route manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, routeManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write route manifest: %w", err)
+	}
+
+	bs.chunks = plan
+
+	bs.ctx.Logger.Info("Split modules into chunks",
+		core.IntField("routes", len(pages)),
+		core.IntField("chunks", len(plan.chunks)))
+
+	return nil
+}
+
+// chunkBuildOptions are the esbuild options for bundling the documents,
+// given by their module files, from the page modules in entries.
+func chunkBuildOptions(outDir string, entries []api.EntryPoint, documents map[string]string, sourceMaps, hydrate bool) api.BuildOptions {
+	sourceMap := api.SourceMapNone
+	if sourceMaps {
+		sourceMap = api.SourceMapLinked
+	}
+
+	options := api.BuildOptions{
+		EntryPointsAdvanced: entries,
+		AbsWorkingDir:       outDir,
+		Outdir:              outDir,
+		EntryNames:          chunksDir + "/[name]",
+		ChunkNames:          chunksDir + "/shared-[hash]",
+		Bundle:              true,
+		Splitting:           true,
+		Format:              api.FormatESModule,
+		Target:              api.ESNext,
+		Sourcemap:           sourceMap,
+		Metafile:            true,
+		Write:               true,
+		AllowOverwrite:      true,
+		LogLevel:            api.LogLevelSilent,
+		Plugins:             []api.Plugin{documentsOnlyPlugin(outDir, documents)},
+	}
+	if hydrate {
+		// The hydration support has to load before Lit does, so it comes
+		// ahead of everything, including imports of shared chunks
+		options.Banner = map[string]string{"js": "import '" + emitter.HydrateSupport + "';"}
+	}
+	return options
+}
+
+// documentsOnlyPlugin keeps everything but documents out of the chunks.
+// Packages are left to the import map, and scripts and builtin modules are
+// imported from their absolute URL in the output.
+func documentsOnlyPlugin(outDir string, documents map[string]string) api.Plugin {
+	return api.Plugin{
+		Name: "jawt-documents",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				if args.Kind == api.ResolveEntryPoint {
+					return api.OnResolveResult{}, nil
+				}
+				spec := args.Path
+				if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+					return api.OnResolveResult{Path: spec, External: true}, nil
+				}
+
+				file := filepath.Join(args.ResolveDir, filepath.FromSlash(spec))
+				if _, ok := documents[file]; ok {
+					return api.OnResolveResult{Path: file}, nil
+				}
+				rel, err := filepath.Rel(outDir, file)
+				if err != nil {
+					return api.OnResolveResult{}, err
+				}
+				return api.OnResolveResult{Path: "/" + filepath.ToSlash(rel), External: true}, nil
+			})
+		},
+	}
+}
+
+// esbuildMetafile is the part of esbuild's metafile that describes outputs.
+type esbuildMetafile struct {
+	Outputs map[string]struct {
+		EntryPoint string              `json:"entryPoint"`
+		Inputs     map[string]struct{} `json:"inputs"`
+	} `json:"outputs"`
+}
+
+// chunkPlanFromMetafile works out which chunk holds which documents from the
+// metafile of the build. Paths in it are relative to outDir. It also returns
+// the module files that were bundled. Every page must have a chunk of its
+// own.
+func chunkPlanFromMetafile(outDir, metafile string, documents map[string]string, pages []string) (*chunkPlan, []string, error) {
+	var meta esbuildMetafile
+	if err := json.Unmarshal([]byte(metafile), &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to read esbuild metafile: %w", err)
+	}
+
+	plan := &chunkPlan{
+		of:      make(map[string]*chunk),
+		preload: make(map[string][]string),
+	}
+	var bundled []string
+
+	outputs := make([]string, 0, len(meta.Outputs))
+	for out := range meta.Outputs {
+		outputs = append(outputs, out)
+	}
+	sort.Strings(outputs)
+
+	for _, out := range outputs {
+		if !strings.HasSuffix(out, ".js") {
+			continue
+		}
+		output := meta.Outputs[out]
+		c := &chunk{url: "/" + path.Clean(filepath.ToSlash(out))}
+		for input := range output.Inputs {
+			file := filepath.Join(outDir, filepath.FromSlash(input))
+			if doc, ok := documents[file]; ok {
+				c.modules = append(c.modules, doc)
+				bundled = append(bundled, file)
+			}
+		}
+		sort.Strings(c.modules)
+		plan.chunks = append(plan.chunks, c)
+
+		if output.EntryPoint != "" {
+			if doc, ok := documents[filepath.Join(outDir, filepath.FromSlash(output.EntryPoint))]; ok {
+				plan.of[doc] = c
+			}
+		}
+	}
+
+	for _, page := range pages {
+		if _, err := plan.chunkFor(page); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return plan, bundled, nil
+}
+
+// chunkFor returns the chunk that renders page.
+func (p *chunkPlan) chunkFor(page string) (*chunk, error) {
+	c, ok := p.of[page]
+	if !ok {
+		return nil, fmt.Errorf("no chunk was bundled for page %s", page)
+	}
+	return c, nil
+}
+
+// lazyDocuments returns the documents that are imported lazily somewhere,
+// sorted.
+func lazyDocuments(docs map[string]*ast.Document) []string {
+	seen := make(map[string]bool)
+	var lazy []string
+	for _, doc := range docs {
+		for _, imp := range doc.Imports {
+			if imp.Kind == ast.ImportComponent && imp.Lazy && imp.ResolvedPath != "" && !seen[imp.ResolvedPath] {
+				if _, ok := docs[imp.ResolvedPath]; ok {
+					seen[imp.ResolvedPath] = true
+					lazy = append(lazy, imp.ResolvedPath)
+				}
+			}
+		}
+	}
+	sort.Strings(lazy)
+	return lazy
+}
@@ -0,0 +1,143 @@
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestSplitChunks(t *testing.T) {
+	// Both pages use the layout. Only the blog uses the card, which lazily
+	// loads the tree. The index also imports a script.
+	root := t.TempDir()
+	out := t.TempDir()
+	ctx := &core.JawtContext{
+		ProjectConfig: core.DefaultProjectConfig(),
+		Paths: &core.ProjectPaths{
+			ProjectRoot:   root,
+			AppDir:        filepath.Join(root, "app"),
+			ComponentsDir: filepath.Join(root, "components"),
+			ScriptsDir:    filepath.Join(root, "scripts"),
+			SrcDir:        filepath.Join(root, ".jawt", "src"),
+			UserSrcDir:    filepath.Join(root, ".jawt", "src", "user"),
+		},
+		Logger:       core.NewDefaultLogger(core.ErrorLevel),
+		BuildOptions: &core.BuildOptions{},
+	}
+	bs := NewBuildSystem(ctx, nil)
+
+	index := filepath.Join(root, "app", "index.jml")
+	blog := filepath.Join(root, "app", "blog.jml")
+	layout := filepath.Join(root, "components", "layout.jml")
+	card := filepath.Join(root, "components", "card.jml")
+	tree := filepath.Join(root, "components", "tree.jml")
+	for _, p := range []string{index, blog, layout, tree} {
+		bs.asts[p] = &ast.Document{}
+	}
+	bs.asts[card] = &ast.Document{Imports: []*ast.Import{{Kind: ast.ImportComponent, Lazy: true, ResolvedPath: tree}}}
+	bs.pages[index] = &PageInfo{Route: "/"}
+	bs.pages[blog] = &PageInfo{Route: "/blog"}
+
+	modules := map[string]string{
+		"user/app/index.js": "import { html } from 'lit';\n" +
+			"import '../components/layout.js';\n" +
+			"import { track } from '../utils.js';\n" +
+			"track(html`index`);\n" +
+			"customElements.define('page-index', class extends HTMLElement {});\n",
+		"user/app/blog.js": "import Layout, { helper } from '../components/layout.js';\n" +
+			"import { Card } from '../components/card.js';\n" +
+			"export const snippet = `\n" +
+			"import fake from './nowhere.js';\n" +
+			"export default fake;\n" +
+			"`;\n" +
+			"customElements.define('page-blog', class extends HTMLElement {});\n" +
+			"console.log(Layout, helper, Card);\n",
+		"user/components/layout.js": "const helper = 1;\n" +
+			"export { helper };\n" +
+			"export default class Layout extends HTMLElement {}\n" +
+			"customElements.define('jawt-layout', Layout);\n",
+		"user/components/card.js": "export * from './tree-types.js';\n" +
+			"export class Card extends HTMLElement {}\n" +
+			"void import('./tree.js');\n",
+		"user/components/tree-types.js": "export const kinds = [];\n",
+		"user/components/tree.js":       "customElements.define('jawt-tree', class extends HTMLElement {});\n",
+		"user/utils.js":                 "export function track(v) { return v; }\n",
+	}
+	writeTree(t, out, modules)
+
+	if err := bs.splitChunks(out, map[string]string{"lit": "/vendor/lit/index.js"}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(url string) string {
+		content, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(strings.TrimPrefix(url, "/"))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	if got := bs.chunks.of[index].url; got != "/chunks/page-index.js" {
+		t.Errorf("Expected the index chunk at /chunks/page-index.js, got %s", got)
+	}
+	blogChunk := read(bs.chunks.of[blog].url)
+	if !strings.Contains(blogChunk, "import fake from './nowhere.js';\nexport default fake;") {
+		t.Errorf("Expected template literals to be left alone, got:\n%s", blogChunk)
+	}
+	if !strings.Contains(read("/chunks/page-index.js"), `"/user/utils.js"`) {
+		t.Errorf("Expected the script to stay a separate module")
+	}
+
+	// The layout is shared and the tree is loaded lazily, so each is in a
+	// chunk of its own
+	chunkOf := make(map[string]string)
+	for _, c := range bs.chunks.chunks {
+		for _, m := range c.modules {
+			chunkOf[m] = c.url
+		}
+	}
+	if chunkOf[layout] == chunkOf[index] || chunkOf[layout] == chunkOf[blog] || !strings.HasPrefix(chunkOf[layout], "/chunks/shared-") {
+		t.Errorf("Expected the layout in a shared chunk, got %v", chunkOf)
+	}
+	if chunkOf[card] != chunkOf[blog] || chunkOf[tree] != "/chunks/jawt-tree.js" {
+		t.Errorf("Expected the card in the blog chunk and the tree apart, got %v", chunkOf)
+	}
+
+	for _, name := range []string{"user/app/index.js", "user/components/layout.js", "user/components/tree.js"} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed once bundled", name)
+		}
+	}
+
+	var manifest map[string]routeManifestEntry
+	if err := json.Unmarshal([]byte(read("/"+routeManifestName)), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if entry := manifest["/blog"]; entry.Tag != "page-blog" || entry.Chunk != bs.chunks.of[blog].url || len(entry.Preload) < 2 {
+		t.Errorf("Expected the blog route with its chunk and preloads, got %+v", entry)
+	}
+}
+
+func TestChunkPlanFromMetafileNeedsEveryPage(t *testing.T) {
+	out := t.TempDir()
+	index := filepath.Join(out, "app", "index.jml")
+	blog := filepath.Join(out, "app", "blog.jml")
+	documents := map[string]string{
+		filepath.Join(out, "user", "app", "index.js"): index,
+		filepath.Join(out, "user", "app", "blog.js"):  blog,
+	}
+	metafile := `{"outputs": {"chunks/page-index.js": {"entryPoint": "user/app/index.js", "inputs": {"user/app/index.js": {}}}}}`
+
+	if _, _, err := chunkPlanFromMetafile(out, metafile, documents, []string{index}); err != nil {
+		t.Errorf("Expected a plan for the index, got %v", err)
+	}
+	_, _, err := chunkPlanFromMetafile(out, metafile, documents, []string{index, blog})
+	if err == nil || !strings.Contains(err.Error(), blog) {
+		t.Errorf("Expected an error naming the blog page, got %v", err)
+	}
+}
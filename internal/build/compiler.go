@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"github.com/yasufadhili/jawt/internal/core"
 	"github.com/yasufadhili/jawt/internal/process"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -17,6 +20,8 @@ func NewCompilerRunner(ctx *core.JawtContext) *CompilerRunner {
 	return &CompilerRunner{ctx: ctx}
 }
 
+// RunTSC compiles the workspace sources. Builds are incremental: tsc keeps
+// its build info in the cache directory and only rechecks changed files.
 func (cr *CompilerRunner) RunTSC() error {
 	cr.ctx.Logger.Info("Running TypeScript compiler")
 
@@ -25,7 +30,39 @@ func (cr *CompilerRunner) RunTSC() error {
 		return fmt.Errorf("tsc not found: %w. Please ensure TypeScript is installed", err)
 	}
 
-	cmd := exec.Command(tscPath, "--project", cr.ctx.Paths.TSConfigPath)
+	// Each build directory keeps build info of its own. tsc is given the
+	// directory rather than reading it from the shared tsconfig, so that a
+	// production build never writes into the one a dev server is using.
+	buildInfo := "tsc" + strings.TrimPrefix(filepath.Base(cr.ctx.Paths.BuildDir), "build") + ".tsbuildinfo"
+	args := []string{"--project", cr.ctx.Paths.TSConfigPath, "--outDir", cr.ctx.Paths.BuildDir}
+	if cr.ctx.BuildOptions.Minify {
+		args = append(args, "--removeComments")
+	}
+	if cr.ctx.BuildOptions.Library {
+		args = append(args, "--declaration")
+	}
+	if !cr.ctx.BuildOptions.SourceMaps {
+		args = append(args, "--sourceMap", "false")
+	}
+	buildInfoPath := filepath.Join(cr.ctx.Paths.CacheDir, buildInfo)
+	args = append(args, "--incremental", "--tsBuildInfoFile", buildInfoPath)
+
+	// tsc trusts its build info and won't re-emit output that has been
+	// deleted since, or that was emitted with other options, so start over
+	// when the build directory is empty or the options have changed.
+	optionsPath := buildInfoPath + ".options"
+	options := strings.Join(args, "\n")
+	if entries, err := os.ReadDir(cr.ctx.Paths.BuildDir); err != nil || len(entries) == 0 {
+		_ = os.Remove(buildInfoPath)
+	}
+	if previous, err := os.ReadFile(optionsPath); err != nil || string(previous) != options {
+		_ = os.Remove(buildInfoPath)
+	}
+	if err := os.WriteFile(optionsPath, []byte(options), 0644); err != nil {
+		return fmt.Errorf("failed to record tsc options: %w", err)
+	}
+
+	cmd := exec.Command(tscPath, args...)
 	cmd.Dir = cr.ctx.Paths.JawtDir // Run from the .jawt directory
 
 	stdout, err := cmd.StdoutPipe()
@@ -67,11 +104,16 @@ func (cr *CompilerRunner) RunTailwind() error {
 		return fmt.Errorf("tailwindcss not found: %w. Please ensure tailwindcss is installed", err)
 	}
 
-	cmd := exec.Command(tailwindPath,
-		"-i", cr.ctx.Paths.TailwindConfigPath,
+	args := []string{
+		"-i", tailwindInputPath(cr.ctx.Paths),
 		"-o", cr.ctx.Paths.TailwindCSSPath,
 		"--config", cr.ctx.Paths.TailwindConfigPath,
-	)
+	}
+	if cr.ctx.BuildOptions.Minify {
+		args = append(args, "--minify")
+	}
+
+	cmd := exec.Command(tailwindPath, args...)
 	cmd.Dir = cr.ctx.Paths.JawtDir // Run from the .jawt directory
 
 	stdout, err := cmd.StdoutPipe()
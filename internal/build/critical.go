@@ -0,0 +1,347 @@
+package build
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+)
+
+// cssRule is a rule or at-rule of a stylesheet. Rules inside grouping
+// at-rules such as @media are parsed into children; every other block is
+// kept as written.
+type cssRule struct {
+	prelude  string // selectors, or the at-rule and its condition
+	body     string // declarations, if the rule has a block
+	children []cssRule
+	block    bool // false for statements like @charset
+	nested   bool // true if the block holds rules rather than declarations
+}
+
+// groupingAtRules contain rules rather than declarations.
+var groupingAtRules = []string{"@media", "@supports", "@container", "@layer", "@document"}
+
+// parseCSS splits a stylesheet into its rules. It understands enough of CSS
+// to split Tailwind's output: comments, strings, escapes and nested blocks.
+func parseCSS(src string) []cssRule {
+	var rules []cssRule
+	i := 0
+	for i < len(src) {
+		start := i
+		end, delim := scanCSS(src, i, "{;")
+		prelude := strings.TrimSpace(stripCSSComments(src[start:end]))
+		if end >= len(src) {
+			if prelude != "" {
+				rules = append(rules, cssRule{prelude: prelude})
+			}
+			break
+		}
+
+		if delim == ';' {
+			rules = append(rules, cssRule{prelude: prelude})
+			i = end + 1
+			continue
+		}
+
+		close := matchingBrace(src, end)
+		body := src[end+1 : close]
+		rule := cssRule{prelude: prelude, block: true}
+		if isGroupingAtRule(prelude) {
+			rule.nested = true
+			rule.children = parseCSS(body)
+		} else {
+			rule.body = strings.TrimSpace(body)
+		}
+		if prelude != "" || rule.body != "" || len(rule.children) > 0 {
+			rules = append(rules, rule)
+		}
+		i = close + 1
+	}
+	return rules
+}
+
+// scanCSS returns the index of the first of stop at the top level from i,
+// skipping strings, comments and escapes, or len(src).
+func scanCSS(src string, i int, stop string) (int, byte) {
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\\':
+			i += 2
+			continue
+		case c == '"' || c == '\'':
+			i = skipCSSString(src, i)
+			continue
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(src)
+			}
+			continue
+		case strings.IndexByte(stop, c) >= 0:
+			return i, c
+		}
+		i++
+	}
+	return len(src), 0
+}
+
+// matchingBrace returns the index of the brace closing the block opened at
+// open, or the end of src if it is never closed.
+func matchingBrace(src string, open int) int {
+	depth := 0
+	i := open
+	for {
+		j, c := scanCSS(src, i, "{}")
+		if j >= len(src) {
+			return len(src) - 1
+		}
+		if c == '{' {
+			depth++
+		} else {
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+		i = j + 1
+	}
+}
+
+func skipCSSString(src string, i int) int {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return i
+}
+
+func stripCSSComments(s string) string {
+	for {
+		start := strings.Index(s, "/*")
+		if start < 0 {
+			return s
+		}
+		end := strings.Index(s[start+2:], "*/")
+		if end < 0 {
+			return s[:start]
+		}
+		s = s[:start] + s[start+2+end+2:]
+	}
+}
+
+func isGroupingAtRule(prelude string) bool {
+	for _, name := range groupingAtRules {
+		if prelude == name || strings.HasPrefix(prelude, name+" ") || strings.HasPrefix(prelude, name+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+var keyframesPattern = regexp.MustCompile(`^@(?:-webkit-)?keyframes\s+(\S+)`)
+
+// criticalCSS returns the rules of a stylesheet that can apply to markup
+// using only the given classes. Rules without class selectors, like the
+// preflight styles, are always kept, and @keyframes are kept when a kept
+// rule refers to them.
+func criticalCSS(rules []cssRule, classes map[string]bool) string {
+	matches := func(r cssRule) bool {
+		if keyframesPattern.MatchString(r.prelude) {
+			return false
+		}
+		return strings.HasPrefix(r.prelude, "@") || selectorMatches(r.prelude, classes)
+	}
+
+	var bodies strings.Builder
+	var collect func([]cssRule)
+	collect = func(rules []cssRule) {
+		for _, r := range rules {
+			bodies.WriteString(r.body)
+			collect(r.children)
+		}
+	}
+	collect(filterCSS(rules, matches))
+	used := bodies.String()
+
+	return writeCSS(filterCSS(rules, func(r cssRule) bool {
+		if m := keyframesPattern.FindStringSubmatch(r.prelude); m != nil {
+			return strings.Contains(used, m[1])
+		}
+		return matches(r)
+	}))
+}
+
+// filterCSS keeps the rules keep accepts. Grouping at-rules are kept if
+// anything inside them is.
+func filterCSS(rules []cssRule, keep func(cssRule) bool) []cssRule {
+	var out []cssRule
+	for _, r := range rules {
+		if r.nested {
+			children := filterCSS(r.children, keep)
+			if len(children) > 0 {
+				r.children = children
+				out = append(out, r)
+			}
+			continue
+		}
+		if keep(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func writeCSS(rules []cssRule) string {
+	var sb strings.Builder
+	for _, r := range rules {
+		sb.WriteString(r.prelude)
+		switch {
+		case r.nested:
+			sb.WriteString("{\n")
+			sb.WriteString(writeCSS(r.children))
+			sb.WriteString("}\n")
+		case r.block:
+			sb.WriteString("{")
+			sb.WriteString(r.body)
+			sb.WriteString("}\n")
+		default:
+			sb.WriteString(";\n")
+		}
+	}
+	return sb.String()
+}
+
+// selectorMatches reports whether any selector in a selector list uses only
+// classes from the set. Selectors without classes always match.
+func selectorMatches(list string, classes map[string]bool) bool {
+	for _, sel := range splitSelectors(list) {
+		ok := true
+		for _, class := range selectorClasses(sel) {
+			if !classes[class] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSelectors splits a selector list at its top-level commas.
+func splitSelectors(list string) []string {
+	var out []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; c {
+		case '\\':
+			i++
+		case '"', '\'':
+			i = skipCSSString(list, i) - 1
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, list[start:])
+}
+
+// selectorClasses returns the unescaped class names in a selector, so
+// `.sm\:w-1\/2:hover` gives "sm:w-1/2".
+func selectorClasses(sel string) []string {
+	var classes []string
+	for i := 0; i < len(sel); i++ {
+		switch sel[i] {
+		case '\\':
+			i++
+		case '"', '\'':
+			i = skipCSSString(sel, i) - 1
+		case '[':
+			// Attribute selectors can contain dots
+			for i < len(sel) && sel[i] != ']' {
+				if sel[i] == '\\' {
+					i++
+				}
+				i++
+			}
+		case '.':
+			var name strings.Builder
+			j := i + 1
+			for j < len(sel) {
+				c := sel[j]
+				if c == '\\' && j+1 < len(sel) {
+					r, n := cssEscape(sel[j+1:])
+					name.WriteString(r)
+					j += 1 + n
+					continue
+				}
+				if c == '-' || c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+					name.WriteByte(c)
+					j++
+					continue
+				}
+				break
+			}
+			if name.Len() > 0 {
+				classes = append(classes, name.String())
+			}
+			i = j - 1
+		}
+	}
+	return classes
+}
+
+// cssEscape decodes the escape after a backslash, returning the text it
+// stands for and how many bytes it used. Hex escapes, like the "\32 " that
+// Tailwind writes for a leading 2, end at an optional space.
+func cssEscape(s string) (string, int) {
+	n := 0
+	for n < len(s) && n < 6 && isHexDigit(s[n]) {
+		n++
+	}
+	if n == 0 {
+		return s[:1], 1
+	}
+	code, _ := strconv.ParseUint(s[:n], 16, 32)
+	if n < len(s) && s[n] == ' ' {
+		n++
+	}
+	return string(rune(code)), n
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// routeClasses returns the classes used by a page and every component it
+// reaches, lazily or not, since all of them are prerendered.
+func (bs *BuildSystem) routeClasses(page string, docs map[string]*ast.Document) map[string]bool {
+	used, _ := reachableFromPages([]string{page}, docs)
+
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	classes := make(map[string]bool)
+	for doc := range used {
+		for _, class := range bs.classes[doc] {
+			classes[class] = true
+		}
+	}
+	return classes
+}
@@ -0,0 +1,51 @@
+package build
+
+import "testing"
+
+func TestCriticalCSS(t *testing.T) {
+	stylesheet := `/*! tailwindcss v3.4.1 | MIT License | https://tailwindcss.com */
+*,::after,::before{box-sizing:border-box}
+body{margin:0;line-height:inherit}
+.flex{display:flex}
+.grid{display:grid}
+.w-1\/2{width:50%}
+.\32xl\:text-lg{font-size:1.125rem}
+[class~="x.y"]{color:red}
+.animate-spin{animation:spin 1s linear infinite}
+.animate-ping{animation:ping 1s infinite}
+@keyframes spin{to{transform:rotate(360deg)}}
+@keyframes ping{75%,to{transform:scale(2);opacity:0}}
+.group:hover .group-hover\:underline{text-decoration-line:underline}
+.content-\[\'\{\}\'\]{--tw-content:'{}'}
+@media (min-width:640px){.sm\:flex{display:flex}.sm\:grid{display:grid}}
+@media (min-width:768px){.md\:grid{display:grid}}
+`
+	classes := map[string]bool{
+		"flex":                  true,
+		"w-1/2":                 true,
+		"2xl:text-lg":           true,
+		"animate-spin":          true,
+		"group":                 true,
+		"group-hover:underline": true,
+		"content-['{}']":        true,
+		"sm:flex":               true,
+	}
+
+	expected := `*,::after,::before{box-sizing:border-box}
+body{margin:0;line-height:inherit}
+.flex{display:flex}
+.w-1\/2{width:50%}
+.\32xl\:text-lg{font-size:1.125rem}
+[class~="x.y"]{color:red}
+.animate-spin{animation:spin 1s linear infinite}
+@keyframes spin{to{transform:rotate(360deg)}}
+.group:hover .group-hover\:underline{text-decoration-line:underline}
+.content-\[\'\{\}\'\]{--tw-content:'{}'}
+@media (min-width:640px){
+.sm\:flex{display:flex}
+}
+`
+	if got := criticalCSS(parseCSS(stylesheet), classes); got != expected {
+		t.Errorf("Expected critical CSS:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,334 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+const (
+	// cspHeadersName is the headers file read by Netlify, Cloudflare Pages
+	// and other static hosts.
+	cspHeadersName = "_headers"
+	cspReportName  = "csp.json"
+)
+
+// cspDirectiveOrder is the order directives are written in.
+var cspDirectiveOrder = []string{
+	"default-src", "script-src", "style-src", "style-src-attr", "img-src",
+	"font-src", "connect-src", "media-src", "frame-src", "object-src",
+	"base-uri", "form-action", "frame-ancestors",
+}
+
+// cspHeaderOnly directives are ignored in a <meta> policy.
+var cspHeaderOnly = map[string]bool{"frame-ancestors": true, "report-uri": true, "sandbox": true}
+
+// contentSecurityPolicy is the policy of one route, as sources by directive.
+type contentSecurityPolicy map[string][]string
+
+func (p contentSecurityPolicy) add(directive string, sources ...string) {
+	for _, src := range sources {
+		found := false
+		for _, existing := range p[directive] {
+			if existing == src {
+				found = true
+				break
+			}
+		}
+		if !found {
+			p[directive] = append(p[directive], src)
+		}
+	}
+}
+
+// String writes the policy. Directives that can't be used in a <meta>
+// policy are left out if meta is set.
+func (p contentSecurityPolicy) String(meta bool) string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		if !meta || !cspHeaderOnly[name] {
+			names = append(names, name)
+		}
+	}
+	rank := make(map[string]int, len(cspDirectiveOrder))
+	for i, name := range cspDirectiveOrder {
+		rank[name] = i + 1
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rank[names[i]], rank[names[j]]
+		if ri == 0 {
+			ri = len(rank) + 1
+		}
+		if rj == 0 {
+			rj = len(rank) + 1
+		}
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = strings.TrimSpace(name + " " + strings.Join(p[name], " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+var (
+	// inlineScriptPattern and inlineStylePattern match the inline elements
+	// the prerenderer writes. External scripts have a src and are skipped.
+	inlineScriptPattern = regexp.MustCompile(`(?s)<script(\s[^>]*)?>(.*?)</script>`)
+	inlineStylePattern  = regexp.MustCompile(`(?s)<style(?:\s[^>]*)?>(.*?)</style>`)
+	styleAttrPattern    = regexp.MustCompile(`\sstyle="([^"]*)"`)
+
+	externalImportPattern = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*)['"](https?://[^'"]+)['"]`)
+	externalFetchPattern  = regexp.MustCompile("(?:\\bfetch|\\bnew\\s+(?:WebSocket|EventSource))\\s*\\(\\s*['\"`]((?:https?|wss?)://[^'\"`]+)")
+)
+
+// basePolicy is the part of every route's policy that doesn't depend on
+// what the route contains.
+func (bs *BuildSystem) basePolicy() contentSecurityPolicy {
+	p := contentSecurityPolicy{}
+	p.add("default-src", "'self'")
+	p.add("script-src", "'self'")
+	p.add("style-src", "'self'")
+	// Image placeholders are data URIs
+	p.add("img-src", "'self'", "data:")
+	p.add("object-src", "'none'")
+	p.add("base-uri", "'self'")
+	p.add("form-action", "'self'")
+	p.add("frame-ancestors", "'self'")
+	return p
+}
+
+// routePolicy works out the policy of a rendered page: hashes of its
+// inline scripts, styles and style attributes, and the origins its modules
+// and documents load from.
+func (bs *BuildSystem) routePolicy(page, rendered, outDir string, docs map[string]*ast.Document) contentSecurityPolicy {
+	p := bs.basePolicy()
+
+	for _, m := range inlineScriptPattern.FindAllStringSubmatch(rendered, -1) {
+		if strings.Contains(m[1], " src=") {
+			continue
+		}
+		p.add("script-src", cspHash(m[2]))
+	}
+	for _, m := range inlineStylePattern.FindAllStringSubmatch(rendered, -1) {
+		p.add("style-src", cspHash(m[1]))
+	}
+	// Attributes are found in the prerendered markup; the same values are
+	// in the templates the components render in the browser.
+	for _, m := range styleAttrPattern.FindAllStringSubmatch(rendered, -1) {
+		p.add("style-src-attr", "'unsafe-hashes'", cspHash(html.UnescapeString(m[1])))
+	}
+
+	// Modules loaded by the page, including lazily
+	measurer := &bundleMeasurer{outDir: outDir}
+	entry := ""
+	if bs.chunks != nil {
+		// Prerender has checked that every page has a chunk
+		if c, err := bs.chunks.chunkFor(page); err == nil {
+			entry = c.url
+		}
+	}
+	if entry != "" {
+		for _, module := range measurer.modules(entry) {
+			content, err := os.ReadFile(measurer.file(module))
+			if err != nil {
+				continue
+			}
+			for _, m := range externalImportPattern.FindAllStringSubmatch(string(content), -1) {
+				p.add("script-src", cspOrigin(m[1]))
+			}
+			for _, m := range externalFetchPattern.FindAllStringSubmatch(string(content), -1) {
+				p.add("connect-src", "'self'", cspOrigin(m[1]))
+			}
+		}
+	}
+
+	used, _ := reachableFromPages([]string{page}, docs)
+	for doc := range used {
+		if d, ok := docs[doc]; ok {
+			ast.Walk(&cspOriginCollector{policy: p}, d)
+		}
+	}
+
+	for directive, sources := range bs.ctx.ProjectConfig.CSP.Directives {
+		if _, ok := p[directive]; !ok && directive != "default-src" {
+			// A new directive replaces default-src for its resources, so
+			// it keeps allowing the site itself
+			p.add(directive, "'self'")
+		}
+		p.add(directive, sources...)
+	}
+
+	return p
+}
+
+// cspOriginCollector records external image sources and form targets.
+type cspOriginCollector struct {
+	ast.BaseVisitor
+	policy contentSecurityPolicy
+}
+
+func (c *cspOriginCollector) VisitElement(el *ast.Element) {
+	name := el.Name
+	if emitter.IsImageElement(name) {
+		name = "Image"
+	}
+	directives := map[string]map[string]string{
+		"Image": {"src": "img-src", "srcset": "img-src"},
+		"Form":  {"action": "form-action"},
+	}[name]
+	for _, prop := range el.Properties {
+		directive, ok := directives[prop.Name]
+		if !ok {
+			continue
+		}
+		lit, ok := prop.Value.(*ast.StringLiteral)
+		if !ok {
+			continue
+		}
+		for _, candidate := range strings.Split(lit.Value, ",") {
+			fields := strings.Fields(candidate)
+			if len(fields) > 0 && (strings.HasPrefix(fields[0], "https://") || strings.HasPrefix(fields[0], "http://")) {
+				c.policy.add(directive, cspOrigin(fields[0]))
+			}
+		}
+	}
+}
+
+func cspHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// cspOrigin returns the scheme and host of a URL.
+func cspOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// writeCSPFiles writes the policy of every route as a _headers file and as
+// JSON. Routes are the paths pages are served at.
+func writeCSPFiles(outDir string, policies map[string]contentSecurityPolicy) error {
+	routes := make([]string, 0, len(policies))
+	for route := range policies {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var headers strings.Builder
+	report := make(map[string]interface{}, len(routes))
+	for _, route := range routes {
+		policy := policies[route]
+		fmt.Fprintf(&headers, "%s\n  Content-Security-Policy: %s\n", route, policy.String(false))
+		report[route] = map[string]interface{}{
+			"policy":     policy.String(false),
+			"directives": policy,
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, cspHeadersName), []byte(headers.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", cspHeadersName, err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode content security policy: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, cspReportName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", cspReportName, err)
+	}
+	return nil
+}
+
+// unsafeEvalPattern matches code that evaluates strings.
+var unsafeEvalPattern = regexp.MustCompile("\\beval\\s*\\(|\\bnew\\s+Function\\s*\\(|\\bset(?:Timeout|Interval)\\s*\\(\\s*['\"`]")
+
+// lintCSP warns about code the generated policy blocks: inline event
+// handler attributes and javascript: URLs in JML, which would need
+// 'unsafe-inline', and string evaluation in JML and scripts, which would
+// need 'unsafe-eval'.
+func (bs *BuildSystem) lintCSP(docs map[string]*ast.Document) *diagnostic.Reporter {
+	reporter := diagnostic.NewReporter()
+
+	paths := make([]string, 0, len(docs))
+	for path := range docs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		ast.Walk(&cspLinter{reporter: reporter}, docs[path])
+		bs.lintUnsafeEval(path, reporter)
+	}
+
+	scripts, _ := bs.ctx.Paths.GetTypeScriptFiles()
+	sort.Strings(scripts)
+	for _, path := range scripts {
+		bs.lintUnsafeEval(path, reporter)
+	}
+
+	return reporter
+}
+
+func (bs *BuildSystem) lintUnsafeEval(path string, reporter *diagnostic.Reporter) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for i, line := range strings.Split(string(content), "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "*") {
+			continue
+		}
+		if loc := unsafeEvalPattern.FindStringIndex(line); loc != nil {
+			reporter.Add(diagnostic.NewDiagnostic("CSP_UNSAFE_EVAL",
+				"evaluating strings as code is blocked by the content security policy without 'unsafe-eval'",
+				diagnostic.Position{Line: i + 1, Column: loc[0] + 1, File: path},
+				diagnostic.SeverityWarning, "csp"))
+		}
+	}
+}
+
+type cspLinter struct {
+	ast.BaseVisitor
+	reporter *diagnostic.Reporter
+}
+
+func (l *cspLinter) VisitProperty(p *ast.Property) {
+	// onClick is bound as a listener; onclick would be an inline handler
+	name := strings.ToLower(p.Name)
+	if strings.HasPrefix(name, "on") && len(p.Name) > 2 && p.Name[2] >= 'a' && p.Name[2] <= 'z' {
+		l.reporter.Add(diagnostic.NewDiagnostic("CSP_UNSAFE_INLINE",
+			fmt.Sprintf("%s is an inline event handler, which the content security policy blocks without 'unsafe-inline'; use on%s%s instead",
+				p.Name, strings.ToUpper(p.Name[2:3]), p.Name[3:]),
+			diagnostic.Position{Line: p.Line, Column: p.Column, File: p.File},
+			diagnostic.SeverityWarning, "csp"))
+	}
+}
+
+func (l *cspLinter) VisitStringLiteral(s *ast.StringLiteral) {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(s.Value)), "javascript:") {
+		l.reporter.Add(diagnostic.NewDiagnostic("CSP_UNSAFE_INLINE",
+			"javascript: URLs are blocked by the content security policy without 'unsafe-inline'; use an event handler instead",
+			diagnostic.Position{Line: s.Line, Column: s.Column, File: s.File},
+			diagnostic.SeverityWarning, "csp"))
+	}
+}
@@ -0,0 +1,42 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestRoutePolicy(t *testing.T) {
+	bs := &BuildSystem{ctx: &core.JawtContext{ProjectConfig: &core.ProjectConfig{}}}
+	bs.ctx.ProjectConfig.CSP.Directives = map[string][]string{
+		"connect-src": {"https://api.example.com"},
+	}
+
+	rendered := `<!DOCTYPE html>
+<html lang="en">
+<head>
+<script type="importmap">{"imports":{}}</script>
+<script src="/vendor/polyfill.js"></script>
+<style>.flex{display:flex}</style>
+</head>
+<body>
+<img style="background:url(&#34;data:x&#34;)">
+<script type="module">
+import('/chunks/page-index.js');
+</script>
+</body>
+</html>`
+
+	policy := bs.routePolicy("/p/app/index.jml", rendered, t.TempDir(), nil)
+
+	expected := "default-src 'self'; " +
+		"script-src 'self' " + cspHash(`{"imports":{}}`) + " " + cspHash("\nimport('/chunks/page-index.js');\n") + "; " +
+		"style-src 'self' " + cspHash(".flex{display:flex}") + "; " +
+		"style-src-attr 'unsafe-hashes' " + cspHash(`background:url("data:x")`) + "; " +
+		"img-src 'self' data:; " +
+		"connect-src 'self' https://api.example.com; " +
+		"object-src 'none'; base-uri 'self'; form-action 'self'"
+	if got := policy.String(true); got != expected {
+		t.Errorf("Expected policy:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,195 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+// importCycle is a cycle that an import would close. It holds a diagnostic
+// for every import along the cycle, by the document the import is in.
+type importCycle map[string]*diagnostic.Diagnostic
+
+// setDependencies replaces the edges from path with its resolved imports.
+// Scripts are added to the graph as they are imported and removed once
+// nothing imports them. An import that would close a cycle is left out of the graph and every
+// import along the cycle is recorded as an error for its document, since a
+// component can't contain itself.
+func (bs *BuildSystem) setDependencies(path string, imports []*ast.Import) {
+	old := append([]string(nil), bs.depGraph.GetDependencies(path)...)
+	for _, dep := range old {
+		bs.depGraph.RemoveDependency(path, dep)
+	}
+
+	var added []*ast.Import
+	var cycles []importCycle
+	for _, imp := range imports {
+		if imp.Kind == ast.ImportScript {
+			if err := bs.depGraph.AddNode(imp.ResolvedPath, DocumentTypeScript); err != nil {
+				bs.ctx.Logger.Error("Failed to add script to dependency graph",
+					core.StringField("path", imp.ResolvedPath),
+					core.ErrorField(err))
+				continue
+			}
+		}
+		err := bs.depGraph.AddDependency(path, imp.ResolvedPath)
+		if err == nil {
+			added = append(added, imp)
+			continue
+		}
+
+		var cycle *CycleError
+		if errors.As(err, &cycle) {
+			cycles = append(cycles, bs.importCycle(imp, cycle))
+			continue
+		}
+		bs.ctx.Logger.Error("Failed to add dependency to graph",
+			core.StringField("from", path),
+			core.StringField("to", imp.ResolvedPath),
+			core.ErrorField(err))
+	}
+	bs.pruneScripts(old)
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.imports[path] = added
+	if len(cycles) == 0 {
+		delete(bs.cycles, path)
+		return
+	}
+	bs.cycles[path] = cycles
+}
+
+// importCycle makes the diagnostics for the cycle that imp would close. The
+// other imports of the cycle are edges of the graph already, so they are
+// found among the imports recorded for their documents.
+func (bs *BuildSystem) importCycle(imp *ast.Import, cycle *CycleError) importCycle {
+	c := importCycle{cycle.Path[0]: bs.cycleDiagnostic(imp, cycle)}
+
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+	for i := 1; i < len(cycle.Path)-1; i++ {
+		from, to := cycle.Path[i], cycle.Path[i+1]
+		for _, edge := range bs.imports[from] {
+			if edge.ResolvedPath == to {
+				c[from] = bs.cycleDiagnostic(edge, cycle)
+				break
+			}
+		}
+	}
+	return c
+}
+
+func (bs *BuildSystem) cycleDiagnostic(imp *ast.Import, cycle *CycleError) *diagnostic.Diagnostic {
+	names := make([]string, len(cycle.Path))
+	for i, p := range cycle.Path {
+		names[i] = filepath.ToSlash(bs.ctx.Paths.GetRelativePath(p))
+	}
+
+	msg := fmt.Sprintf("circular import: %s. A component can't contain itself; "+
+		"if the recursion ends at runtime, as in a tree view, use `import lazy component %s from %q`",
+		strings.Join(names, " → "), imp.Name, imp.Path)
+
+	return diagnostic.NewDiagnostic("IMPORT_CYCLE", msg,
+		diagnostic.Position{Line: imp.Line, Column: imp.Column, File: imp.File},
+		diagnostic.SeverityError, "resolver")
+}
+
+// cycleDiagnostics returns the errors for the imports of path that are part
+// of a cycle, whichever document's import closes it.
+func (bs *BuildSystem) cycleDiagnostics(path string) []*diagnostic.Diagnostic {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	closers := make([]string, 0, len(bs.cycles))
+	for closer := range bs.cycles {
+		closers = append(closers, closer)
+	}
+	sort.Strings(closers)
+
+	var diagnostics []*diagnostic.Diagnostic
+	for _, closer := range closers {
+		for _, c := range bs.cycles[closer] {
+			if d, ok := c[path]; ok {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// cycleMembers returns the documents with an import along a cycle.
+func (bs *BuildSystem) cycleMembers() map[string]bool {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	members := make(map[string]bool)
+	for _, cycles := range bs.cycles {
+		for _, c := range cycles {
+			for path := range c {
+				members[path] = true
+			}
+		}
+	}
+	return members
+}
+
+// retryCycles re-reads the imports of every document that had a cyclic
+// import, other than except, after the graph has changed. It returns the
+// documents along cycles that are gone, which need compiling again.
+func (bs *BuildSystem) retryCycles(except string) []string {
+	before := bs.cycleMembers()
+
+	bs.mu.RLock()
+	var paths []string
+	for path := range bs.cycles {
+		if path != except {
+			paths = append(paths, path)
+		}
+	}
+	bs.mu.RUnlock()
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		doc, ok := bs.GetDocumentInfo(path)
+		if !ok {
+			continue
+		}
+		deps, err := bs.extractDependencies(doc)
+		if err != nil {
+			continue
+		}
+		bs.setDependencies(path, deps)
+	}
+
+	after := bs.cycleMembers()
+	var fixed []string
+	for path := range before {
+		if !after[path] && path != except {
+			if _, ok := bs.GetDocumentInfo(path); ok {
+				fixed = append(fixed, path)
+			}
+		}
+	}
+	sort.Strings(fixed)
+	return fixed
+}
+
+// cycleCount returns the number of imports that would close a cycle, which
+// is the number of cycles.
+func (bs *BuildSystem) cycleCount() int {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	n := 0
+	for _, cycles := range bs.cycles {
+		n += len(cycles)
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestSetDependenciesReportsEveryImportOfACycle(t *testing.T) {
+	// a imports b, b imports c and c imports a
+	root := t.TempDir()
+	ctx := &core.JawtContext{
+		Paths:  &core.ProjectPaths{ProjectRoot: root},
+		Logger: core.NewDefaultLogger(core.ErrorLevel),
+	}
+	bs := NewBuildSystem(ctx, nil)
+
+	paths := []string{
+		filepath.Join(root, "components", "a.jml"),
+		filepath.Join(root, "components", "b.jml"),
+		filepath.Join(root, "components", "c.jml"),
+	}
+	for _, path := range paths {
+		if err := bs.depGraph.AddNode(path, DocumentTypeComponent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	imports := make([][]*ast.Import, len(paths))
+	for i, path := range paths {
+		next := paths[(i+1)%len(paths)]
+		imports[i] = []*ast.Import{{
+			Position:     ast.Position{Line: i + 1, Column: 1, File: path},
+			Kind:         ast.ImportComponent,
+			Name:         "Next",
+			Path:         "./" + filepath.Base(next),
+			ResolvedPath: next,
+		}}
+		bs.setDependencies(path, imports[i])
+	}
+
+	if n := bs.cycleCount(); n != 1 {
+		t.Errorf("Expected 1 cycle, got %d", n)
+	}
+	total := 0
+	for i, path := range paths {
+		diagnostics := bs.cycleDiagnostics(path)
+		total += len(diagnostics)
+		if len(diagnostics) != 1 {
+			t.Errorf("Expected 1 diagnostic for %s, got %d", filepath.Base(path), len(diagnostics))
+			continue
+		}
+		if pos := diagnostics[0].Pos; pos.File != path || pos.Line != i+1 {
+			t.Errorf("Expected the diagnostic at the import in %s, got %s:%d", filepath.Base(path), pos.File, pos.Line)
+		}
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 diagnostics, got %d", total)
+	}
+
+	// Once b no longer imports c, c's import closes nothing
+	bs.setDependencies(paths[1], nil)
+	bs.setDependencies(paths[2], imports[2])
+	for _, path := range paths {
+		if diagnostics := bs.cycleDiagnostics(path); len(diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics for %s once the cycle is gone, got %d", filepath.Base(path), len(diagnostics))
+		}
+	}
+}
+
+func TestSetDependenciesAddsScripts(t *testing.T) {
+	root := t.TempDir()
+	ctx := &core.JawtContext{
+		Paths:  &core.ProjectPaths{ProjectRoot: root},
+		Logger: core.NewDefaultLogger(core.ErrorLevel),
+	}
+	bs := NewBuildSystem(ctx, nil)
+
+	page := filepath.Join(root, "app", "index.jml")
+	script := filepath.Join(root, "scripts", "analytics.ts")
+	if err := bs.depGraph.AddNode(page, DocumentTypePage); err != nil {
+		t.Fatal(err)
+	}
+	bs.setDependencies(page, []*ast.Import{{
+		Kind:         ast.ImportScript,
+		Name:         "analytics",
+		Path:         "scripts/analytics",
+		ResolvedPath: script,
+	}})
+
+	if deps := bs.depGraph.GetDependencies(page); len(deps) != 1 || deps[0] != script {
+		t.Errorf("Expected an edge from the page to the script, got %v", deps)
+	}
+	if scripts := bs.depGraph.GetNodesByType(DocumentTypeScript); len(scripts) != 1 || scripts[0] != script {
+		t.Errorf("Expected the script as a node, got %v", scripts)
+	}
+	if dependents := bs.depGraph.GetTransitiveDependents(script); len(dependents) != 1 || dependents[0] != page {
+		t.Errorf("Expected the page to be rebuilt when the script changes, got %v", dependents)
+	}
+
+	// Once nothing imports it, the script leaves the graph
+	bs.setDependencies(page, nil)
+	if scripts := bs.depGraph.GetNodesByType(DocumentTypeScript); len(scripts) != 0 {
+		t.Errorf("Expected no scripts once the import is gone, got %v", scripts)
+	}
+}
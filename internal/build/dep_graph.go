@@ -2,25 +2,37 @@ package build
 
 import (
 	"fmt"
+	"github.com/yasufadhili/jawt/internal/ast"
 	"github.com/yasufadhili/jawt/internal/core"
-	"regexp"
+	"sort"
+	"strings"
 )
 
-// ExtractDependencies extracts component and script dependencies from a JML file.
-func ExtractDependencies(content string) []string {
-	re := regexp.MustCompile(`import\s+(component|script)\s+\w+\s+from\s+"([^"]+)"`)
-	matches := re.FindAllStringSubmatch(content, -1)
-
-	var dependencies []string
-	for _, match := range matches {
-		if len(match) == 3 {
-			dependencies = append(dependencies, match[2])
+// ExtractDependencies returns the component and script imports of a
+// document. Builtin imports such as `import browser` are not dependencies.
+func ExtractDependencies(doc *ast.Document) []*ast.Import {
+	var dependencies []*ast.Import
+	for _, imp := range doc.Imports {
+		if imp.Kind == ast.ImportComponent || imp.Kind == ast.ImportScript {
+			dependencies = append(dependencies, imp)
 		}
 	}
 
 	return dependencies
 }
 
+// CycleError is returned by AddDependency for an edge that would close a
+// cycle. The graph itself always stays acyclic.
+type CycleError struct {
+	// Path is the cycle the edge would create, starting and ending with
+	// the node the edge is from: a -> b -> a.
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "adding dependency " + e.Path[0] + " -> " + e.Path[1] + " would create a cycle: " + strings.Join(e.Path, " -> ")
+}
+
 type DependencyGraph interface {
 	// Core graph operations
 	AddNode(path string, docType DocumentType) error
@@ -38,6 +50,8 @@ type DependencyGraph interface {
 	GetCycles() [][]string
 	GetTopologicalOrder() ([]string, error)
 	GetCompilationOrder() ([]string, error)
+	GetCompilationLevels() ([][]string, error)
+	GetSubgraphLevels(nodes []string) ([][]string, error)
 
 	// Validation
 	ValidateGraph() error
@@ -130,7 +144,7 @@ func (dg *dependencyGraph) AddDependency(from, to string) error {
 	}
 
 	if from == to {
-		return fmt.Errorf("cannot add self-dependency for node %s", from)
+		return &CycleError{Path: []string{from, to}}
 	}
 
 	// Check if dependency already exists
@@ -141,7 +155,7 @@ func (dg *dependencyGraph) AddDependency(from, to string) error {
 	}
 
 	if dg.wouldCreateCycle(from, to) {
-		return fmt.Errorf("adding dependency %s -> %s would create a cycle", from, to)
+		return &CycleError{Path: append([]string{from}, dg.GetShortestPath(to, from)...)}
 	}
 
 	// Add dependency
@@ -356,6 +370,72 @@ func (dg *dependencyGraph) GetCompilationOrder() ([]string, error) {
 	return compilationOrder, nil
 }
 
+// GetCompilationLevels groups the nodes by depth. Every node's dependencies
+// are in earlier levels, so the nodes within a level can be compiled in any
+// order, or at the same time. Each level is sorted.
+func (dg *dependencyGraph) GetCompilationLevels() ([][]string, error) {
+	return dg.GetSubgraphLevels(dg.GetAllNodes())
+}
+
+// GetSubgraphLevels groups the given nodes by depth within the subgraph
+// they form, like GetCompilationLevels. Edges to nodes outside the subgraph
+// are ignored, so a changed node and its dependents can be ordered without
+// the rest of the graph. Unknown nodes are skipped.
+func (dg *dependencyGraph) GetSubgraphLevels(nodes []string) ([][]string, error) {
+	inSubgraph := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if _, exists := dg.nodes[node]; exists {
+			inSubgraph[node] = true
+		}
+	}
+
+	const visiting = -1
+	depth := make(map[string]int, len(inSubgraph))
+	var levelOf func(node string) (int, error)
+	levelOf = func(node string) (int, error) {
+		if d, ok := depth[node]; ok {
+			if d == visiting {
+				return 0, fmt.Errorf("cannot group nodes into levels: cycle through %s", node)
+			}
+			return d, nil
+		}
+		depth[node] = visiting
+
+		d := 0
+		for _, dep := range dg.edges[node] {
+			if !inSubgraph[dep] {
+				continue
+			}
+			l, err := levelOf(dep)
+			if err != nil {
+				return 0, err
+			}
+			if l+1 > d {
+				d = l + 1
+			}
+		}
+		depth[node] = d
+		return d, nil
+	}
+
+	var levels [][]string
+	for node := range inSubgraph {
+		d, err := levelOf(node)
+		if err != nil {
+			return nil, err
+		}
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], node)
+	}
+	for _, level := range levels {
+		sort.Strings(level)
+	}
+
+	return levels, nil
+}
+
 // Validation
 
 func (dg *dependencyGraph) ValidateGraph() error {
@@ -517,7 +597,11 @@ func (dg *dependencyGraph) collectTransitiveDependents(node string, visited map[
 	visited[node] = true
 
 	for _, dependent := range dg.reverseEdges[node] {
-		*result = append(*result, dependent)
+		// A dependent reached through two paths, as in a diamond, is only
+		// listed once
+		if !visited[dependent] {
+			*result = append(*result, dependent)
+		}
 		dg.collectTransitiveDependents(dependent, visited, result)
 	}
 }
@@ -1,26 +1,36 @@
 package build
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
 	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
 )
 
 func TestExtractDependencies(t *testing.T) {
-	content := `
-		_doctype page home
-
-		import component Layout from "components/layout"
-		import script analytics from "scripts/analytics"
-		import browser
-
-		Page {
-			title: "Welcome"
-		}
-	`
-
-	dependencies := ExtractDependencies(content)
+	// _doctype page home
+	//
+	// import component Layout from "components/layout"
+	// import script analytics from "scripts/analytics"
+	// import browser
+	doc := &ast.Document{
+		DocType: ast.DocTypePage,
+		Name:    "home",
+		Imports: []*ast.Import{
+			{Kind: ast.ImportComponent, Name: "Layout", Path: "components/layout"},
+			{Kind: ast.ImportScript, Name: "analytics", Path: "scripts/analytics"},
+			{Kind: ast.ImportBuiltin, Name: "browser"},
+		},
+		Root: &ast.Element{Name: "Page"},
+	}
+
+	var dependencies []string
+	for _, imp := range ExtractDependencies(doc) {
+		dependencies = append(dependencies, imp.Path)
+	}
 	sort.Strings(dependencies)
 
 	expected := []string{"components/layout", "scripts/analytics"}
@@ -97,6 +107,15 @@ func TestDependencyGraph_CycleDetection(t *testing.T) {
 		t.Error("Expected error when adding dependency that creates cycle")
 	}
 
+	// The error shows the whole cycle
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Expected a CycleError, got: %v", err)
+	}
+	if expected := []string{"C", "A", "B", "C"}; !reflect.DeepEqual(cycle.Path, expected) {
+		t.Errorf("Expected cycle %v, got %v", expected, cycle.Path)
+	}
+
 	// Verify cycle was prevented
 	if dg.HasCycle() {
 		t.Error("Cycle was not prevented")
@@ -182,6 +201,106 @@ func TestDependencyGraph_CompilationOrder(t *testing.T) {
 	}
 }
 
+func TestDependencyGraph_CompilationLevels(t *testing.T) {
+	dg := NewDependencyGraph()
+
+	// A and B both use C; B also uses D, which uses C
+	dg.AddNode("A", DocumentTypePage)
+	dg.AddNode("B", DocumentTypePage)
+	dg.AddNode("C", DocumentTypeComponent)
+	dg.AddNode("D", DocumentTypeComponent)
+	dg.AddNode("E", DocumentTypeComponent)
+
+	dg.AddDependency("A", "C")
+	dg.AddDependency("B", "C")
+	dg.AddDependency("B", "D")
+	dg.AddDependency("D", "C")
+
+	levels, err := dg.GetCompilationLevels()
+	if err != nil {
+		t.Fatalf("Expected no error getting compilation levels, got: %v", err)
+	}
+
+	expected := [][]string{{"C", "E"}, {"A", "D"}, {"B"}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, levels)
+	}
+}
+
+func TestDependencyGraph_DependentsSubgraphChain(t *testing.T) {
+	dg := NewDependencyGraph()
+
+	// page -> layout -> nav -> link, plus an unrelated footer
+	dg.AddNode("page", DocumentTypePage)
+	dg.AddNode("layout", DocumentTypeComponent)
+	dg.AddNode("nav", DocumentTypeComponent)
+	dg.AddNode("link", DocumentTypeComponent)
+	dg.AddNode("footer", DocumentTypeComponent)
+
+	dg.AddDependency("page", "layout")
+	dg.AddDependency("layout", "nav")
+	dg.AddDependency("nav", "link")
+	dg.AddDependency("page", "footer")
+
+	// Changing link rebuilds every ancestor, one per level, innermost first
+	dependents := dg.GetTransitiveDependents("link")
+	levels, err := dg.GetSubgraphLevels(append([]string{"link"}, dependents...))
+	if err != nil {
+		t.Fatalf("Expected no error getting subgraph levels, got: %v", err)
+	}
+
+	expected := [][]string{{"link"}, {"nav"}, {"layout"}, {"page"}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, levels)
+	}
+}
+
+func TestDependencyGraph_DependentsSubgraphDiamond(t *testing.T) {
+	dg := NewDependencyGraph()
+
+	// page uses header and sidebar, which both use button
+	dg.AddNode("page", DocumentTypePage)
+	dg.AddNode("header", DocumentTypeComponent)
+	dg.AddNode("sidebar", DocumentTypeComponent)
+	dg.AddNode("button", DocumentTypeComponent)
+	dg.AddNode("icon", DocumentTypeComponent)
+
+	dg.AddDependency("page", "header")
+	dg.AddDependency("page", "sidebar")
+	dg.AddDependency("header", "button")
+	dg.AddDependency("sidebar", "button")
+	dg.AddDependency("header", "icon")
+
+	dependents := dg.GetTransitiveDependents("button")
+	sort.Strings(dependents)
+	if expected := []string{"header", "page", "sidebar"}; !reflect.DeepEqual(dependents, expected) {
+		t.Errorf("Expected dependents %v, got %v", expected, dependents)
+	}
+
+	// page is compiled once, after both sides of the diamond; icon is
+	// outside the subgraph and not rebuilt
+	levels, err := dg.GetSubgraphLevels(append([]string{"button"}, dependents...))
+	if err != nil {
+		t.Fatalf("Expected no error getting subgraph levels, got: %v", err)
+	}
+
+	expected := [][]string{{"button"}, {"header", "sidebar"}, {"page"}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, levels)
+	}
+
+	// Without the changed node, its direct dependents come first
+	levels, err = dg.GetSubgraphLevels(dependents)
+	if err != nil {
+		t.Fatalf("Expected no error getting subgraph levels, got: %v", err)
+	}
+
+	expected = [][]string{{"header", "sidebar"}, {"page"}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, levels)
+	}
+}
+
 func TestDependencyGraph_TransitiveDependencies(t *testing.T) {
 	dg := NewDependencyGraph()
 
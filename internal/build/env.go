@@ -0,0 +1,186 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+// envModuleName is the builtin module holding the public environment. JML
+// imports it with `import env`, scripts from "@jawt/env".
+const envModuleName = "env.ts"
+
+// envModuleExports are the upper case names the env module exports besides
+// the public variables. They look like variables to checkEnvReferences.
+var envModuleExports = map[string]bool{"MODE": true, "FEATURES": true}
+
+// writeEnvModule loads the environment of the build and writes the public
+// variables and the feature flags of the project to the env module as typed
+// constants. Nothing without the public prefix is written.
+func (bs *BuildSystem) writeEnvModule() error {
+	env, err := core.LoadEnv(bs.ctx.Paths.ProjectRoot, bs.ctx.BuildOptions.Mode)
+	if err != nil {
+		return fmt.Errorf("failed to load environment: %w", err)
+	}
+
+	path := filepath.Join(bs.ctx.Paths.InternalSrcDir, envModuleName)
+	if err := os.WriteFile(path, []byte(envModule(bs.ctx.BuildOptions.Mode, env, bs.ctx.ProjectConfig.Features)), 0644); err != nil {
+		return fmt.Errorf("failed to write env module: %w", err)
+	}
+	return nil
+}
+
+// envModule returns the source of the env module for the public variables
+// in env and the feature flags.
+func envModule(mode string, env map[string]string, features map[string]bool) string {
+	var names []string
+	for name := range env {
+		if core.IsPublicEnv(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	quote := func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Generated by jawt from .env files and the environment. Only variables\n")
+	fmt.Fprintf(&sb, "// starting with %s are included.\n\n", core.PublicEnvPrefix)
+	fmt.Fprintf(&sb, "export const MODE: string = %s;\n\n", quote(mode))
+
+	for _, name := range names {
+		fmt.Fprintf(&sb, "export const %s = %s;\n", name, quote(env[name]))
+	}
+	if len(names) > 0 {
+		sb.WriteString("\n")
+	}
+
+	if len(names) == 0 {
+		sb.WriteString("export type PublicEnv = never;\n\n")
+		sb.WriteString("const values: Record<PublicEnv, string> = {};\n\n")
+	} else {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quote(name)
+		}
+		fmt.Fprintf(&sb, "export type PublicEnv = %s;\n\n", strings.Join(quoted, " | "))
+		fmt.Fprintf(&sb, "const values: Record<PublicEnv, string> = { %s };\n\n", strings.Join(names, ", "))
+	}
+
+	flags := make([]string, 0, len(features))
+	for name := range features {
+		flags = append(flags, name)
+	}
+	sort.Strings(flags)
+	if len(flags) == 0 {
+		sb.WriteString("export type Feature = never;\n\n")
+	} else {
+		quoted := make([]string, len(flags))
+		for i, name := range flags {
+			quoted[i] = quote(name)
+		}
+		fmt.Fprintf(&sb, "export type Feature = %s;\n\n", strings.Join(quoted, " | "))
+	}
+	sb.WriteString("export const FEATURES: Record<Feature, boolean> = {")
+	for i, name := range flags {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, " %s: %t", quote(name), features[name])
+	}
+	if len(flags) > 0 {
+		sb.WriteString(" ")
+	}
+	sb.WriteString("};\n\n")
+
+	sb.WriteString("export function isProd(): boolean {\n  return MODE === \"production\";\n}\n\n")
+	sb.WriteString("export function isDev(): boolean {\n  return MODE === \"development\";\n}\n\n")
+	sb.WriteString("export function getEnv(name: PublicEnv): string {\n  return values[name];\n}\n\n")
+	sb.WriteString("export function isEnabled(feature: Feature): boolean {\n  return FEATURES[feature];\n}\n")
+	return sb.String()
+}
+
+// envReferencePatterns match the ways client code names an environment
+// variable. The first group is the variable, or for an import of the env
+// module, the list of names imported.
+var envReferencePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bgetEnv\(\s*['"` + "`" + `]([A-Za-z_][A-Za-z0-9_]*)['"` + "`" + `]`),
+	regexp.MustCompile(`\b(?:process|import\.meta)\.env\.([A-Za-z_][A-Za-z0-9_]*)`),
+	regexp.MustCompile(`(?:^|[^.\w])env\.([A-Z_][A-Z0-9_]*)\b`),
+	envImportPattern,
+}
+
+var envImportPattern = regexp.MustCompile(`\bimport\s*\{([^}]*)\}\s*from\s*['"](?:@jawt/env|[^'"]*/internal/env)(?:\.js)?['"]`)
+
+// checkEnvReferences reports every variable without the public prefix that
+// a JML document or script refers to. Such code would either break in the
+// browser or, if the value were inlined, leak it.
+func (bs *BuildSystem) checkEnvReferences() *diagnostic.Reporter {
+	reporter := diagnostic.NewReporter()
+
+	bs.mu.RLock()
+	files := make([]string, 0, len(bs.docs))
+	for path := range bs.docs {
+		files = append(files, path)
+	}
+	bs.mu.RUnlock()
+	scripts, _ := bs.ctx.Paths.GetTypeScriptFiles()
+	files = append(files, scripts...)
+	sort.Strings(files)
+
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			for _, pattern := range envReferencePatterns {
+				for _, m := range pattern.FindAllStringSubmatchIndex(line, -1) {
+					names := []string{line[m[2]:m[3]]}
+					if pattern == envImportPattern {
+						names = importedEnvNames(names[0])
+					}
+					for _, name := range names {
+						if envModuleExports[name] || core.IsPublicEnv(name) {
+							continue
+						}
+						reporter.Add(diagnostic.NewDiagnostic("ENV_PRIVATE",
+							fmt.Sprintf("%s is not a public environment variable; only variables starting with %s can be used in client code",
+								name, core.PublicEnvPrefix),
+							diagnostic.Position{Line: i + 1, Column: m[2] + strings.Index(line[m[2]:m[3]], name) + 1, File: path},
+							diagnostic.SeverityError, "env"))
+					}
+				}
+			}
+		}
+	}
+
+	return reporter
+}
+
+// importedEnvNames returns the variables in an import list like
+// `{ A, B as b, getEnv }`. The module's functions and types aren't
+// upper case, so they are left out.
+func importedEnvNames(list string) []string {
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "type ")
+		if i := strings.Index(part, " as "); i >= 0 {
+			part = strings.TrimSpace(part[:i])
+		}
+		if part != "" && part == strings.ToUpper(part) {
+			names = append(names, part)
+		}
+	}
+	return names
+}
@@ -0,0 +1,39 @@
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestCheckEnvReferences(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app/index.jml": "import env\n\nPage {\n    title: env.getEnv(\"JAWT_PUBLIC_TITLE\")\n    Text { content: env.API_SECRET }\n    Text { visible: env.FEATURES.beta }\n}\n",
+		"scripts/api.ts": "import { getEnv, MODE, FEATURES, DATABASE_URL as url } from '@jawt/env';\n" +
+			"export const base = getEnv('JAWT_PUBLIC_API_URL');\n" +
+			"export const key = import.meta.env.STRIPE_KEY;\n",
+	}
+	writeTree(t, root, files)
+
+	ctx := &core.JawtContext{Paths: &core.ProjectPaths{ProjectRoot: root, ScriptsDir: filepath.Join(root, "scripts")}}
+	bs := NewBuildSystem(ctx, nil)
+	bs.docs[filepath.Join(root, "app", "index.jml")] = &DocumentInfo{}
+
+	var got []string
+	for _, d := range bs.checkEnvReferences().Errors() {
+		got = append(got, fmt.Sprintf("%s:%d:%d %s", filepath.Base(d.Pos.File), d.Pos.Line, d.Pos.Column, strings.Fields(d.Message)[0]))
+	}
+	expected := []string{
+		"index.jml:5:25 API_SECRET",
+		"api.ts:1:34 DATABASE_URL",
+		"api.ts:3:36 STRIPE_KEY",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
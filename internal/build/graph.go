@@ -0,0 +1,238 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+// GraphOptions selects the part of the dependency graph to export and how
+// to print it.
+type GraphOptions struct {
+	Format    string   // "dot", "mermaid" or "json"
+	From      string   // only this document and what it depends on
+	Reverse   bool     // with From, what depends on it instead
+	PagesOnly bool     // only pages and what they depend on
+	Why       []string // the shortest path from one document to another
+}
+
+// graphView is the selected nodes and edges, keyed by project-relative path.
+type graphView struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ExportGraph discovers the project and writes its dependency graph to w.
+func ExportGraph(ctx *core.JawtContext, w io.Writer, opts GraphOptions) error {
+	bs := NewBuildSystem(ctx, nil)
+	if err := bs.DiscoverProject(); err != nil {
+		return err
+	}
+
+	view, err := bs.graphView(opts)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case "", "dot":
+		writeDOT(w, view)
+	case "mermaid":
+		writeMermaid(w, view)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(view)
+	default:
+		return fmt.Errorf("unknown graph format %q; use dot, mermaid or json", opts.Format)
+	}
+
+	return nil
+}
+
+func (bs *BuildSystem) graphView(opts GraphOptions) (*graphView, error) {
+	dg := bs.depGraph
+
+	selected := make(map[string]bool)
+	var pathEdges []graphEdge
+
+	switch {
+	case len(opts.Why) > 0:
+		if len(opts.Why) != 2 {
+			return nil, fmt.Errorf("why needs two documents")
+		}
+		from, err := bs.graphNodePath(opts.Why[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := bs.graphNodePath(opts.Why[1])
+		if err != nil {
+			return nil, err
+		}
+		path := dg.GetShortestPath(from, to)
+		if path == nil {
+			return nil, fmt.Errorf("%s does not depend on %s", opts.Why[0], opts.Why[1])
+		}
+		for i, node := range path {
+			selected[node] = true
+			if i > 0 {
+				pathEdges = append(pathEdges, graphEdge{From: path[i-1], To: node})
+			}
+		}
+
+	case opts.From != "":
+		from, err := bs.graphNodePath(opts.From)
+		if err != nil {
+			return nil, err
+		}
+		selected[from] = true
+		related := dg.GetTransitiveDependencies(from)
+		if opts.Reverse {
+			related = dg.GetTransitiveDependents(from)
+		}
+		for _, node := range related {
+			selected[node] = true
+		}
+
+	default:
+		for _, node := range dg.GetAllNodes() {
+			selected[node] = true
+		}
+	}
+
+	if opts.PagesOnly {
+		used := make(map[string]bool)
+		for _, page := range dg.GetNodesByType(DocumentTypePage) {
+			used[page] = true
+			for _, dep := range dg.GetTransitiveDependencies(page) {
+				used[dep] = true
+			}
+		}
+		for node := range selected {
+			if !used[node] {
+				delete(selected, node)
+			}
+		}
+	}
+
+	view := &graphView{Nodes: []graphNode{}, Edges: []graphEdge{}}
+	for node := range selected {
+		// Scripts are the only nodes that aren't documents
+		docType := bs.getDocumentTypeString(DocumentTypeScript)
+		if doc, ok := bs.GetDocumentInfo(node); ok {
+			docType = bs.getDocumentTypeString(doc.Type)
+		}
+		view.Nodes = append(view.Nodes, graphNode{ID: bs.graphID(node), Type: docType})
+	}
+
+	if pathEdges == nil {
+		for node := range selected {
+			for _, dep := range dg.GetDependencies(node) {
+				if selected[dep] {
+					pathEdges = append(pathEdges, graphEdge{From: node, To: dep})
+				}
+			}
+		}
+	}
+	for _, e := range pathEdges {
+		view.Edges = append(view.Edges, graphEdge{From: bs.graphID(e.From), To: bs.graphID(e.To)})
+	}
+
+	sort.Slice(view.Nodes, func(i, j int) bool { return view.Nodes[i].ID < view.Nodes[j].ID })
+	sort.Slice(view.Edges, func(i, j int) bool {
+		if view.Edges[i].From != view.Edges[j].From {
+			return view.Edges[i].From < view.Edges[j].From
+		}
+		return view.Edges[i].To < view.Edges[j].To
+	})
+
+	return view, nil
+}
+
+// graphNodePath finds the document or script a command line argument
+// names. It may be absolute or relative to the project root, and documents
+// may leave out .jml.
+func (bs *BuildSystem) graphNodePath(name string) (string, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(bs.ctx.Paths.ProjectRoot, filepath.FromSlash(name))
+	}
+	if bs.isScriptPath(path) {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%s is not a script in this project", name)
+		}
+		return path, nil
+	}
+	if !strings.HasSuffix(path, ".jml") {
+		if _, err := os.Stat(path); err != nil {
+			path += ".jml"
+		}
+	}
+
+	if _, ok := bs.GetDocumentInfo(path); !ok {
+		return "", fmt.Errorf("%s is not a document in this project", name)
+	}
+	return path, nil
+}
+
+func (bs *BuildSystem) graphID(path string) string {
+	return filepath.ToSlash(bs.ctx.Paths.GetRelativePath(path))
+}
+
+func writeDOT(w io.Writer, view *graphView) {
+	fmt.Fprintln(w, "digraph jawt {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, n := range view.Nodes {
+		shape := "ellipse"
+		switch n.Type {
+		case "page":
+			shape = "box"
+		case "script":
+			shape = "note"
+		}
+		fmt.Fprintf(w, "  %q [shape=%s];\n", n.ID, shape)
+	}
+	for _, e := range view.Edges {
+		fmt.Fprintf(w, "  %q -> %q;\n", e.From, e.To)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func writeMermaid(w io.Writer, view *graphView) {
+	ids := make(map[string]string, len(view.Nodes))
+
+	fmt.Fprintln(w, "graph LR")
+	for i, n := range view.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+
+		label := strings.ReplaceAll(n.ID, `"`, "#quot;")
+		switch n.Type {
+		case "page":
+			fmt.Fprintf(w, "  %s[\"%s\"]\n", id, label)
+		case "script":
+			fmt.Fprintf(w, "  %s[/\"%s\"/]\n", id, label)
+		default:
+			fmt.Fprintf(w, "  %s(\"%s\")\n", id, label)
+		}
+	}
+	for _, e := range view.Edges {
+		fmt.Fprintf(w, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+}
@@ -0,0 +1,44 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/process"
+)
+
+// runBuildScripts runs the preBuild or postBuild scripts of the project one
+// after the other, from the project root. It stops at the first script
+// that fails or runs out of time.
+func runBuildScripts(ctx *core.JawtContext, stage string, scripts []string, outDir string) error {
+	if len(scripts) == 0 {
+		return nil
+	}
+
+	env := append(os.Environ(),
+		"JAWT_MODE="+ctx.BuildOptions.Mode,
+		"JAWT_OUT_DIR="+outDir,
+		"JAWT_PROJECT_ROOT="+ctx.Paths.ProjectRoot,
+		"JAWT_HOOK="+stage,
+	)
+	timeout := time.Duration(ctx.ProjectConfig.ScriptTimeout) * time.Second
+
+	for i, script := range scripts {
+		ctx.Logger.Info("Running build script",
+			core.StringField("stage", stage),
+			core.StringField("script", script))
+
+		options := process.ShellOptions(script, ctx.Paths.ProjectRoot).
+			WithEnv(env).
+			WithTimeout(timeout)
+		name := fmt.Sprintf("%s[%d]", stage, i)
+		if err := process.Run(context.Background(), name, options, ctx.Logger); err != nil {
+			return fmt.Errorf("%s script %q: %w", stage, script, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestRunBuildScripts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts use sh syntax")
+	}
+
+	root := t.TempDir()
+	ctx := &core.JawtContext{
+		ProjectConfig: core.DefaultProjectConfig(),
+		Paths:         &core.ProjectPaths{ProjectRoot: root},
+		Logger:        core.NewDefaultLogger(core.ErrorLevel),
+		BuildOptions:  &core.BuildOptions{Mode: "production"},
+	}
+
+	err := runBuildScripts(ctx, "preBuild", []string{
+		`echo "$JAWT_MODE $JAWT_OUT_DIR $JAWT_HOOK" > env.txt`,
+		"exit 3",
+		"touch never.txt",
+	}, "/tmp/dist")
+	if err == nil || !strings.Contains(err.Error(), "exit 3") {
+		t.Fatalf("Expected the second script to fail, got %v", err)
+	}
+
+	env, err := os.ReadFile(filepath.Join(root, "env.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(env)); got != "production /tmp/dist preBuild" {
+		t.Errorf("Expected the environment to describe the build, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "never.txt")); err == nil {
+		t.Error("Expected scripts after a failure not to run")
+	}
+
+	ctx.ProjectConfig.ScriptTimeout = 1
+	err = runBuildScripts(ctx, "postBuild", []string{"sleep 5"}, "/tmp/dist")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected the script to time out, got %v", err)
+	}
+}
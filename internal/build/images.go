@@ -0,0 +1,345 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+// placeholderWidth is the width of the thumbnail a placeholder is made from.
+// It is scaled up and blurred by the browser, so a few pixels are enough.
+const placeholderWidth = 16
+
+// imageCacheVersion is part of the cache key and changes whenever the
+// cached output of processImage does.
+const imageCacheVersion = 2
+
+// imageInfo describes a processed image and its resized variants.
+type imageInfo struct {
+	Width       int
+	Height      int
+	Variants    []imageVariant // by ascending width; the last is the original
+	Placeholder string         // data URI shown until the image loads, if any
+}
+
+type imageVariant struct {
+	Width int
+	URL   string
+}
+
+// imageCacheEntry is stored next to the cached variants of an image.
+type imageCacheEntry struct {
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Variants    []int  `json:"variants"`
+	Format      string `json:"format"`
+	Placeholder string `json:"placeholder,omitempty"`
+}
+
+func isImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// processImage generates the resized variants of an image asset. Variants
+// are cached in the cache directory by the hash of the source and the
+// configured widths, so unchanged images are not resized again.
+func (ap *assetPipeline) processImage(original *assetFile) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(original.content))
+	if err != nil {
+		ap.ctx.Logger.Debug("Skipping image that cannot be decoded",
+			core.StringField("path", original.source),
+			core.ErrorField(err))
+		return nil
+	}
+
+	info := &imageInfo{Width: cfg.Width, Height: cfg.Height}
+	ap.images[original.rel] = info
+
+	var widths []int
+	for _, w := range ap.ctx.ProjectConfig.Images.Widths {
+		if w < cfg.Width {
+			widths = append(widths, w)
+		}
+	}
+	sort.Ints(widths)
+
+	// Resizing an animation would keep only its first frame.
+	if format == "gif" {
+		if g, err := gif.DecodeAll(bytes.NewReader(original.content)); err == nil && len(g.Image) > 1 {
+			widths = nil
+		}
+	}
+
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+
+	cacheDir := ap.imageCacheDir(original.content, widths)
+	variants, meta, err := ap.readCachedVariants(cacheDir, widths, ext)
+	if err != nil {
+		meta = imageCacheEntry{Width: cfg.Width, Height: cfg.Height, Variants: widths, Format: ext}
+		variants, meta.Placeholder, err = ap.resizeImage(original, widths, format)
+		if err != nil {
+			return fmt.Errorf("failed to resize %s: %w", original.source, err)
+		}
+		ap.writeCachedVariants(cacheDir, meta, variants)
+	}
+	info.Placeholder = meta.Placeholder
+
+	base := strings.TrimSuffix(original.rel, path.Ext(original.rel))
+	for i, w := range widths {
+		f := ap.fingerprint(original.source, fmt.Sprintf("%s-%dw%s", base, w, ext), variants[i])
+		ap.variants = append(ap.variants, f)
+		info.Variants = append(info.Variants, imageVariant{Width: w, URL: f.url})
+	}
+	info.Variants = append(info.Variants, imageVariant{Width: cfg.Width, URL: original.url})
+
+	return nil
+}
+
+// resizeImage returns the encoded variants of an image at each width, and
+// its placeholder.
+func (ap *assetPipeline) resizeImage(original *assetFile, widths []int, format string) ([][]byte, string, error) {
+	src, _, err := image.Decode(bytes.NewReader(original.content))
+	if err != nil {
+		return nil, "", err
+	}
+
+	b := src.Bounds()
+	var out [][]byte
+	for _, w := range widths {
+		h := (b.Dy()*w + b.Dx()/2) / b.Dx()
+		if h < 1 {
+			h = 1
+		}
+		resized := resize(src, w, h)
+
+		var buf bytes.Buffer
+		if format == "jpeg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 82})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, buf.Bytes())
+	}
+
+	placeholder, err := placeholderFor(src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return out, placeholder, nil
+}
+
+// placeholderFor returns an SVG data URI that shows a blurred thumbnail of
+// img. Images with transparent areas get no placeholder, since it would show
+// through once the image has loaded.
+func placeholderFor(img image.Image) (string, error) {
+	if o, ok := img.(interface{ Opaque() bool }); !ok || !o.Opaque() {
+		return "", nil
+	}
+
+	b := img.Bounds()
+	w := placeholderWidth
+	if b.Dx() < w {
+		w = b.Dx()
+	}
+	h := (b.Dy()*w + b.Dx()/2) / b.Dx()
+	if h < 1 {
+		h = 1
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, resize(img, w, h)); err != nil {
+		return "", err
+	}
+	thumb := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// The filter blurs the thumbnail and then makes it fully opaque again,
+	// so the edges don't fade out.
+	svg := fmt.Sprintf(`<svg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 %d %d'>`+
+		`<filter id='b' color-interpolation-filters='sRGB'><feGaussianBlur stdDeviation='1'/>`+
+		`<feComponentTransfer><feFuncA type='discrete' tableValues='1 1'/></feComponentTransfer></filter>`+
+		`<image width='100%%' height='100%%' preserveAspectRatio='none' filter='url(#b)' href='%s'/></svg>`,
+		w, h, thumb)
+
+	return "data:image/svg+xml," + svgEscaper.Replace(svg), nil
+}
+
+// svgEscaper percent-encodes the characters that can't appear as-is in an
+// SVG data URI used in CSS or HTML.
+var svgEscaper = strings.NewReplacer(`"`, "%22", "#", "%23", "<", "%3C", ">", "%3E", "%", "%25")
+
+func (ap *assetPipeline) imageCacheDir(content []byte, widths []int) string {
+	h := sha256.New()
+	h.Write(content)
+	for _, w := range widths {
+		fmt.Fprintf(h, ":%d", w)
+	}
+	fmt.Fprintf(h, ":v%d", imageCacheVersion)
+	return filepath.Join(ap.ctx.Paths.CacheDir, "images", hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+func (ap *assetPipeline) readCachedVariants(dir string, widths []int, ext string) ([][]byte, imageCacheEntry, error) {
+	var meta imageCacheEntry
+	data, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return nil, meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, meta, err
+	}
+
+	var out [][]byte
+	for _, w := range widths {
+		data, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(w)+ext))
+		if err != nil {
+			return nil, meta, err
+		}
+		out = append(out, data)
+	}
+	return out, meta, nil
+}
+
+// writeCachedVariants stores resized variants. Failing to cache is not an
+// error; the image is resized again on the next build.
+func (ap *assetPipeline) writeCachedVariants(dir string, meta imageCacheEntry, variants [][]byte) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ap.ctx.Logger.Warn("Failed to create image cache directory", core.ErrorField(err))
+		return
+	}
+	for i, w := range meta.Variants {
+		if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(w)+meta.Format), variants[i], 0644); err != nil {
+			ap.ctx.Logger.Warn("Failed to cache image variant", core.ErrorField(err))
+			return
+		}
+	}
+
+	data, _ := json.Marshal(meta)
+	if err := os.WriteFile(filepath.Join(dir, "meta.json"), data, 0644); err != nil {
+		ap.ctx.Logger.Warn("Failed to cache image metadata", core.ErrorField(err))
+	}
+}
+
+// resize scales img down to width x height by averaging the source pixels
+// that cover each destination pixel.
+func resize(img image.Image, width, height int) *image.RGBA {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := sy * src.Stride
+				for sx := x0; sx < x1; sx++ {
+					i := row + sx*4
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					bl += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// addImageAttributes fills in the responsive attributes of an Image or img
+// whose src is a processed asset. Attributes the author set are left alone.
+func (ap *assetPipeline) addImageAttributes(el *ast.Element) {
+	src := el.Property("src")
+	if src == nil {
+		return
+	}
+	lit, ok := src.Value.(*ast.StringLiteral)
+	if !ok {
+		return
+	}
+	f, ok := ap.lookupFile(lit.Value)
+	if !ok {
+		return
+	}
+	info, ok := ap.images[f.rel]
+	if !ok {
+		return
+	}
+
+	set := func(name string, value ast.Expression) {
+		if el.Property(name) == nil {
+			el.Properties = append(el.Properties, &ast.Property{Position: src.Position, Name: name, Value: value})
+		}
+	}
+	str := func(s string) ast.Expression { return &ast.StringLiteral{Position: src.Position, Value: s} }
+	num := func(n int) ast.Expression {
+		return &ast.NumberLiteral{Position: src.Position, Value: float64(n), Raw: strconv.Itoa(n)}
+	}
+
+	if len(info.Variants) > 1 {
+		candidates := make([]string, len(info.Variants))
+		for i, v := range info.Variants {
+			candidates[i] = fmt.Sprintf("%s %dw", v.URL, v.Width)
+		}
+		set("srcset", str(strings.Join(candidates, ", ")))
+		set("sizes", str(ap.ctx.ProjectConfig.Images.Sizes))
+	}
+
+	// Intrinsic dimensions let the browser reserve space before the image
+	// loads. An author-set dimension wins, and then neither is added.
+	if el.Property("width") == nil && el.Property("height") == nil {
+		set("width", num(info.Width))
+		set("height", num(info.Height))
+	}
+
+	if info.Placeholder != "" {
+		set("placeholder", str(info.Placeholder))
+	}
+
+	set("loading", str("lazy"))
+	set("decoding", str("async"))
+}
@@ -0,0 +1,70 @@
+package build
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestImageAttributes(t *testing.T) {
+	root := t.TempDir()
+	config := core.DefaultProjectConfig()
+	config.Images.Widths = []int{200}
+	ctx := &core.JawtContext{
+		ProjectConfig: config,
+		Paths: &core.ProjectPaths{
+			ProjectRoot: root,
+			AssetsDir:   filepath.Join(root, "assets"),
+			CacheDir:    filepath.Join(root, ".jawt", "cache"),
+		},
+		Logger: core.NewDefaultLogger(core.ErrorLevel),
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 300))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(ctx.Paths.AssetsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ctx.Paths.AssetsDir, "hero.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ap := newAssetPipeline(ctx)
+	if err := ap.Process(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The built-in and the HTML spelling get the same attributes
+	for _, name := range []string{"Image", "img"} {
+		el := &ast.Element{Name: name, Properties: []*ast.Property{
+			{Name: "src", Value: &ast.StringLiteral{Value: "assets/hero.png"}},
+		}}
+		ap.RewriteReferences(&ast.Document{Root: &ast.Element{Name: "Page", Children: []ast.Node{el}}})
+
+		str := func(prop string) string {
+			p := el.Property(prop)
+			if p == nil {
+				return ""
+			}
+			return p.Value.String()
+		}
+		if srcset := str("srcset"); !strings.Contains(srcset, " 200w") || !strings.Contains(srcset, " 400w") {
+			t.Errorf("Expected a srcset with both widths on %s, got %s", name, srcset)
+		}
+		if str("width") != "400" || str("height") != "300" {
+			t.Errorf("Expected the intrinsic size on %s, got %sx%s", name, str("width"), str("height"))
+		}
+		if str("loading") != `"lazy"` {
+			t.Errorf("Expected lazy loading on %s, got %s", name, str("loading"))
+		}
+	}
+}
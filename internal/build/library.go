@@ -0,0 +1,283 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+// LibraryManifestName is the manifest at the root of a library package.
+const LibraryManifestName = "jawt.lib.json"
+
+const (
+	librarySourceDir = "src" // JML and TypeScript sources, as in the project
+	libraryModuleDir = "lib" // compiled modules and declarations
+)
+
+// LibraryManifest describes a library package to the projects that use it.
+// Paths are relative to the package root and use forward slashes.
+type LibraryManifest struct {
+	Name        string             `json:"name"`
+	Version     string             `json:"version"`
+	Description string             `json:"description,omitempty"`
+	Author      string             `json:"author,omitempty"`
+	Components  []LibraryComponent `json:"components"`
+	Scripts     []LibraryScript    `json:"scripts"`
+	// Files lists every file of the package apart from the manifest,
+	// including the dependencies of the exports.
+	Files []string `json:"files"`
+}
+
+// LibraryComponent is an exported component.
+type LibraryComponent struct {
+	Name   string `json:"name"`
+	Tag    string `json:"tag"`
+	Source string `json:"source"`
+	Module string `json:"module"`
+	Types  string `json:"types"`
+}
+
+// LibraryScript is an exported script.
+type LibraryScript struct {
+	Source string `json:"source"`
+	Module string `json:"module"`
+	Types  string `json:"types"`
+}
+
+// BuildLibrary builds the project as a library package in outDir, which
+// defaults to the configured dist directory. The package holds the compiled
+// modules and declarations of the exports and of everything they import,
+// their sources, so that projects using the library can compile them
+// again, and a manifest describing the exports.
+func BuildLibrary(ctx *core.JawtContext, outDir string) error {
+	if len(ctx.ProjectConfig.Exports) == 0 {
+		return fmt.Errorf("nothing to build: a library needs exports in jawt.project.json")
+	}
+
+	outDir, err := resolveOutputDir(ctx, outDir)
+	if err != nil {
+		return err
+	}
+
+	if err := runBuildScripts(ctx, "preBuild", ctx.ProjectConfig.GetPreBuildScripts(), outDir); err != nil {
+		return err
+	}
+
+	ctx.BuildOptions.Library = true
+	ctx.BuildOptions.Minify = ctx.ProjectConfig.Build.Minify && ctx.JawtConfig.EnableMinification
+	ctx.BuildOptions.SourceMaps = !ctx.BuildOptions.Minify || (ctx.ProjectConfig.SourceMaps && ctx.JawtConfig.EnableSourceMaps)
+	ctx.Paths.SetBuildDir(filepath.Join(ctx.Paths.JawtDir, "build-library"))
+
+	ctx.Logger.Info("Building library",
+		core.StringField("name", ctx.ProjectConfig.App.Name),
+		core.StringField("output", outDir),
+		core.StringField("mode", ctx.BuildOptions.Mode),
+		core.IntField("exports", len(ctx.ProjectConfig.Exports)))
+
+	buildSystem := NewBuildSystem(ctx, nil)
+	if err := buildSystem.Build(); err != nil {
+		return err
+	}
+
+	manifest, files, err := buildSystem.libraryPackage()
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(outDir); err != nil {
+		return fmt.Errorf("failed to clean output directory: %w", err)
+	}
+	for dest, src := range files {
+		target := filepath.Join(outDir, filepath.FromSlash(dest))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", dest, err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", dest, err)
+		}
+	}
+
+	if ctx.BuildOptions.Minify {
+		if err := minifyModules(ctx, filepath.Join(outDir, libraryModuleDir)); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode library manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, LibraryManifestName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write library manifest: %w", err)
+	}
+
+	if err := runBuildScripts(ctx, "postBuild", ctx.ProjectConfig.GetPostBuildScripts(), outDir); err != nil {
+		return err
+	}
+
+	ctx.Logger.Info("Library built",
+		core.StringField("output", outDir),
+		core.IntField("components", len(manifest.Components)),
+		core.IntField("scripts", len(manifest.Scripts)))
+
+	return nil
+}
+
+// exportPaths returns the absolute paths of the exports in the project
+// configuration, components and scripts apart. They aren't checked.
+func (bs *BuildSystem) exportPaths() (components, scripts []string) {
+	for _, export := range bs.ctx.ProjectConfig.Exports {
+		path := bs.ctx.Paths.GetAbsolutePath(filepath.FromSlash(export))
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if isJMLPath(path) {
+			components = append(components, path)
+		} else {
+			scripts = append(scripts, path)
+		}
+	}
+	return components, scripts
+}
+
+// libraryPackage works out the manifest of the library and the files of its
+// package, from their path in the package to the file to copy. The project
+// must have been built.
+func (bs *BuildSystem) libraryPackage() (*LibraryManifest, map[string]string, error) {
+	paths := bs.ctx.Paths
+	components, scripts := bs.exportPaths()
+
+	bs.mu.RLock()
+	docs := make(map[string]*ast.Document, len(bs.asts))
+	for path, doc := range bs.asts {
+		docs[path] = doc
+	}
+	bs.mu.RUnlock()
+
+	for _, path := range components {
+		doc, ok := docs[path]
+		if !ok {
+			return nil, nil, fmt.Errorf("export %s is not a component of the project", paths.GetRelativePath(path))
+		}
+		if doc.DocType == ast.DocTypePage {
+			return nil, nil, fmt.Errorf("export %s is a page; only components can be exported", paths.GetRelativePath(path))
+		}
+	}
+	for _, path := range scripts {
+		if _, ok := within(paths.ScriptsDir, path); !ok {
+			return nil, nil, fmt.Errorf("export %s is not in the scripts directory", paths.GetRelativePath(path))
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, fmt.Errorf("export %s: %w", paths.GetRelativePath(path), err)
+		}
+	}
+
+	// Everything the exports import goes into the package too
+	used, usedScripts := reachableFromPages(components, docs)
+	for _, path := range scripts {
+		usedScripts[path] = true
+	}
+	bs.followScriptImports(usedScripts)
+
+	sources := make([]string, 0, len(used)+len(usedScripts))
+	for path := range used {
+		sources = append(sources, path)
+	}
+	for path := range usedScripts {
+		sources = append(sources, path)
+	}
+	sort.Strings(sources)
+
+	em := emitter.NewEmitter(bs.ctx)
+	files := make(map[string]string)
+	// Where each source and its compiled module end up in the package
+	entries := make(map[string]LibraryScript, len(sources))
+	for _, path := range sources {
+		source := librarySourceDir + "/" + filepath.ToSlash(paths.GetRelativePath(path))
+		files[source] = path
+
+		compiled := strings.TrimPrefix(em.ModuleURL(path), "/")
+		module := libraryModuleDir + "/" + compiled
+		types := strings.TrimSuffix(module, ".js") + ".d.ts"
+		outputs := map[string]string{
+			module: compiled,
+			types:  strings.TrimSuffix(compiled, ".js") + ".d.ts",
+		}
+		if bs.ctx.BuildOptions.SourceMaps {
+			outputs[module+".map"] = compiled + ".map"
+		}
+		for dest, rel := range outputs {
+			src := filepath.Join(paths.BuildDir, filepath.FromSlash(rel))
+			if _, err := os.Stat(src); err != nil {
+				if dest == module+".map" {
+					continue
+				}
+				return nil, nil, fmt.Errorf("compiled output for %s is missing: %w", paths.GetRelativePath(path), err)
+			}
+			files[dest] = src
+		}
+
+		entries[path] = LibraryScript{Source: source, Module: module, Types: types}
+	}
+
+	// The builtin modules the compiled code may import
+	internalDir := filepath.Join(paths.BuildDir, filepath.Base(paths.InternalSrcDir))
+	err := filepath.Walk(internalDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if !bs.ctx.BuildOptions.SourceMaps && strings.HasSuffix(path, ".map") {
+			return nil
+		}
+		rel, err := filepath.Rel(paths.BuildDir, path)
+		if err != nil {
+			return err
+		}
+		files[libraryModuleDir+"/"+filepath.ToSlash(rel)] = path
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("failed to collect builtin modules: %w", err)
+	}
+
+	config := bs.ctx.ProjectConfig
+	manifest := &LibraryManifest{
+		Name:        config.App.Name,
+		Version:     config.App.Version,
+		Description: config.App.Description,
+		Author:      config.App.Author,
+		Components:  []LibraryComponent{},
+		Scripts:     []LibraryScript{},
+		Files:       make([]string, 0, len(files)),
+	}
+	for _, path := range components {
+		entry := entries[path]
+		manifest.Components = append(manifest.Components, LibraryComponent{
+			Name:   docs[path].Name,
+			Tag:    em.TagName(path),
+			Source: entry.Source,
+			Module: entry.Module,
+			Types:  entry.Types,
+		})
+	}
+	for _, path := range scripts {
+		manifest.Scripts = append(manifest.Scripts, entries[path])
+	}
+	for dest := range files {
+		manifest.Files = append(manifest.Files, dest)
+	}
+	sort.Strings(manifest.Files)
+
+	return manifest, files, nil
+}
@@ -0,0 +1,109 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestLibraryPackage(t *testing.T) {
+	root := t.TempDir()
+	config := core.DefaultProjectConfig()
+	config.App.Name = "ui-kit"
+	config.Type = "library"
+	config.Exports = []string{"components/card.jml", "scripts/format.ts"}
+	paths, err := core.NewProjectPaths(root, config, core.DefaultJawtConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// card uses button; format imports util. The compiled output is what
+	// tsc would leave in the build directory.
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rel := range []string{"components/card.jml", "components/button.jml", "components/unused.jml"} {
+		write(filepath.Join(root, rel), "")
+	}
+	write(filepath.Join(root, "scripts/format.ts"), `import { pad } from "./util";`)
+	write(filepath.Join(root, "scripts/util.ts"), "")
+	for _, rel := range []string{
+		"user/components/card.js", "user/components/card.d.ts", "user/components/card.js.map",
+		"user/components/button.js", "user/components/button.d.ts",
+		"user/components/unused.js", "user/components/unused.d.ts",
+		"user/format.js", "user/format.d.ts", "user/util.js", "user/util.d.ts",
+		"internal/env.js", "internal/env.d.ts",
+	} {
+		write(filepath.Join(paths.BuildDir, rel), "")
+	}
+
+	ctx := &core.JawtContext{
+		ProjectConfig: config,
+		Paths:         paths,
+		Logger:        core.NewDefaultLogger(core.ErrorLevel),
+		BuildOptions:  &core.BuildOptions{SourceMaps: true, Library: true},
+	}
+	bs := NewBuildSystem(ctx, nil)
+	card := filepath.Join(root, "components/card.jml")
+	button := filepath.Join(root, "components/button.jml")
+	bs.asts[card] = &ast.Document{DocType: ast.DocTypeComponent, Name: "Card", Imports: []*ast.Import{
+		{Kind: ast.ImportComponent, ResolvedPath: button},
+	}}
+	bs.asts[button] = &ast.Document{DocType: ast.DocTypeComponent, Name: "Button"}
+	bs.asts[filepath.Join(root, "components/unused.jml")] = &ast.Document{DocType: ast.DocTypeComponent, Name: "Unused"}
+
+	manifest, files, err := bs.libraryPackage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedComponents := []LibraryComponent{{
+		Name:   "Card",
+		Tag:    "jawt-card",
+		Source: "src/components/card.jml",
+		Module: "lib/user/components/card.js",
+		Types:  "lib/user/components/card.d.ts",
+	}}
+	if !reflect.DeepEqual(manifest.Components, expectedComponents) {
+		t.Errorf("Expected components %+v, got %+v", expectedComponents, manifest.Components)
+	}
+
+	expectedScripts := []LibraryScript{{
+		Source: "src/scripts/format.ts",
+		Module: "lib/user/format.js",
+		Types:  "lib/user/format.d.ts",
+	}}
+	if !reflect.DeepEqual(manifest.Scripts, expectedScripts) {
+		t.Errorf("Expected scripts %+v, got %+v", expectedScripts, manifest.Scripts)
+	}
+
+	expectedFiles := []string{
+		"lib/internal/env.d.ts", "lib/internal/env.js",
+		"lib/user/components/button.d.ts", "lib/user/components/button.js",
+		"lib/user/components/card.d.ts", "lib/user/components/card.js", "lib/user/components/card.js.map",
+		"lib/user/format.d.ts", "lib/user/format.js",
+		"lib/user/util.d.ts", "lib/user/util.js",
+		"src/components/button.jml", "src/components/card.jml",
+		"src/scripts/format.ts", "src/scripts/util.ts",
+	}
+	if !reflect.DeepEqual(manifest.Files, expectedFiles) {
+		t.Errorf("Expected files %v, got %v", expectedFiles, manifest.Files)
+	}
+	if files["src/components/card.jml"] != card {
+		t.Errorf("Expected the JML source of card to be copied, got %q", files["src/components/card.jml"])
+	}
+
+	config.Exports = []string{"components/missing.jml"}
+	if _, _, err := bs.libraryPackage(); err == nil {
+		t.Error("Expected an export that isn't in the project to fail")
+	}
+}
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+// minifyModules minifies every JavaScript module in outDir except the
+// runtime packages, which are published minified. Source maps that tsc
+// wrote are read back in, so the maps that are kept still lead to the
+// TypeScript.
+func minifyModules(ctx *core.JawtContext, outDir string) error {
+	var entries []string
+	err := filepath.Walk(outDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path == filepath.Join(outDir, vendorDir) {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".js") {
+			entries = append(entries, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list modules: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	sourceMap := api.SourceMapNone
+	if ctx.BuildOptions.SourceMaps {
+		sourceMap = api.SourceMapLinked
+	}
+
+	result := api.Build(api.BuildOptions{
+		EntryPoints:       entries,
+		Outdir:            outDir,
+		Outbase:           outDir,
+		AllowOverwrite:    true,
+		Write:             true,
+		Format:            api.FormatESModule,
+		Target:            api.ESNext,
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		MinifySyntax:      true,
+		Sourcemap:         sourceMap,
+		LogLevel:          api.LogLevelSilent,
+	})
+	if len(result.Errors) > 0 {
+		msg := result.Errors[0]
+		if msg.Location != nil {
+			return fmt.Errorf("failed to minify %s:%d: %s", msg.Location.File, msg.Location.Line, msg.Text)
+		}
+		return fmt.Errorf("failed to minify: %s", msg.Text)
+	}
+
+	ctx.Logger.Info("Minified modules", core.IntField("modules", len(entries)))
+
+	return nil
+}
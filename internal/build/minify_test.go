@@ -0,0 +1,52 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestMinifyModules(t *testing.T) {
+	out := t.TempDir()
+	module := "// Compiled by tsc\n" +
+		"import { html } from 'lit';\n" +
+		"export class Greeting {\n" +
+		"    render() {\n" +
+		"        const greeting = `Hello, ${'world'}`;\n" +
+		"        return html`<p>${greeting}</p>`;\n" +
+		"    }\n" +
+		"}\n"
+	vendor := "export const untouched = 1; // keep\n"
+	files := map[string]string{
+		"user/greeting.js":  module,
+		"vendor/lit/lit.js": vendor,
+	}
+	writeTree(t, out, files)
+
+	ctx := &core.JawtContext{
+		Logger:       core.NewDefaultLogger(core.ErrorLevel),
+		BuildOptions: &core.BuildOptions{Minify: true},
+	}
+	if err := minifyModules(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+
+	minified, err := os.ReadFile(filepath.Join(out, "user", "greeting.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(minified)
+	if strings.Contains(got, "Compiled by tsc") || strings.Contains(got, "greeting") || strings.Count(got, "\n") > 1 {
+		t.Errorf("Expected the module to be minified, got %q", got)
+	}
+	if !strings.Contains(got, "Greeting") || !strings.Contains(got, `from"lit"`) {
+		t.Errorf("Expected the export and the import to survive, got %q", got)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(out, "vendor", "lit", "lit.js")); string(content) != vendor {
+		t.Errorf("Expected runtime packages to be left alone, got %q", content)
+	}
+}
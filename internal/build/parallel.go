@@ -0,0 +1,132 @@
+package build
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+// emitResult is the outcome of emitting one document.
+type emitResult struct {
+	doc      *DocumentInfo
+	reporter *diagnostic.Reporter
+	err      error
+}
+
+// compileLevels compiles documents grouped by dependency level. Documents
+// in a level don't depend on each other and are emitted concurrently; each
+// level finishes before the next starts. The external compilers run once
+// at the end.
+func (bs *BuildSystem) compileLevels(levels [][]string) error {
+	var emitted []*DocumentInfo
+
+	for _, level := range levels {
+		results, err := bs.emitLevel(level)
+		if err != nil {
+			return err
+		}
+
+		// Results are in the order of the level, not of completion, so the
+		// diagnostics read the same on every run.
+		merged := diagnostic.NewReporter()
+		var firstErr error
+		failed := 0
+		for i, result := range results {
+			for _, d := range result.reporter.All() {
+				merged.Add(d)
+			}
+			if result.err != nil {
+				bs.ctx.Logger.Error("Failed to compile document",
+					core.StringField("path", level[i]),
+					core.ErrorField(result.err))
+				if firstErr == nil {
+					firstErr = result.err
+				}
+				failed++
+				continue
+			}
+			if result.doc != nil {
+				emitted = append(emitted, result.doc)
+			}
+		}
+
+		if merged.HasErrors() {
+			diagnostic.NewPrinter().Print(merged)
+		}
+		if failed == 1 {
+			return firstErr
+		}
+		if failed > 1 {
+			return fmt.Errorf("%d documents failed to compile; first error: %w", failed, firstErr)
+		}
+	}
+
+	if len(emitted) == 0 {
+		return nil
+	}
+
+	if err := bs.runExternalCompilers(); err != nil {
+		return err
+	}
+
+	bs.mu.Lock()
+	for _, doc := range emitted {
+		doc.IsCompiled = true
+	}
+	bs.mu.Unlock()
+
+	return nil
+}
+
+// emitLevel emits the documents of one level with a pool of workers. It
+// stops handing out work when the context is cancelled.
+func (bs *BuildSystem) emitLevel(paths []string) ([]emitResult, error) {
+	results := make([]emitResult, len(paths))
+
+	workers := bs.compileConcurrency()
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				reporter := diagnostic.NewReporter()
+				doc, err := bs.emitDocument(paths[i], reporter)
+				results[i] = emitResult{doc: doc, reporter: reporter, err: err}
+			}
+		}()
+	}
+
+	done := bs.ctx.Context().Done()
+feed:
+	for i := range paths {
+		select {
+		case jobs <- i:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := bs.ctx.Context().Err(); err != nil {
+		return nil, fmt.Errorf("compilation cancelled: %w", err)
+	}
+
+	return results, nil
+}
+
+func (bs *BuildSystem) compileConcurrency() int {
+	if n := bs.ctx.JawtConfig.CompileConcurrency; n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
@@ -0,0 +1,200 @@
+package build
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+	"github.com/yasufadhili/jawt/internal/emitter"
+)
+
+// Prerender renders every compiled page to static HTML under outDir.
+// Dynamic routes are expanded using the staticParams declared on the page.
+// Any head markup is added to every page.
+func (bs *BuildSystem) Prerender(outDir string, head ...string) error {
+	bs.ctx.Logger.Info("Prerendering pages", core.StringField("output", outDir))
+
+	bs.mu.RLock()
+	asts := make(map[string]*ast.Document, len(bs.asts))
+	for path, doc := range bs.asts {
+		asts[path] = doc
+	}
+	pages := make([]*PageInfo, 0, len(bs.pages))
+	for _, page := range bs.pages {
+		pages = append(pages, page)
+	}
+	bs.mu.RUnlock()
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Route < pages[j].Route })
+
+	prerenderer := emitter.NewPrerenderer(emitter.NewEmitter(bs.ctx), asts)
+	rendered := 0
+
+	var sitemap []sitemapRoute
+	var policies map[string]contentSecurityPolicy
+	if bs.ctx.ProjectConfig.CSP.Enabled {
+		policies = make(map[string]contentSecurityPolicy)
+		if reporter := bs.lintCSP(asts); reporter.HasWarnings() {
+			diagnostic.NewPrinter().Print(reporter)
+		}
+	}
+
+	// The rules each page uses are inlined, and the full stylesheet loads
+	// after the page has been parsed.
+	var stylesheet []cssRule
+	if bs.ctx.BuildOptions.UsesTailwindCSS {
+		if css, err := os.ReadFile(filepath.Join(outDir, "tailwind.css")); err == nil {
+			stylesheet = parseCSS(string(css))
+		} else {
+			bs.ctx.Logger.Warn("Failed to read stylesheet for critical CSS", core.ErrorField(err))
+		}
+	}
+
+	for _, page := range pages {
+		doc, ok := asts[page.AbsPath]
+		if !ok {
+			bs.ctx.Logger.Warn("Skipping page that has not been compiled",
+				core.StringField("path", page.RelPath))
+			continue
+		}
+
+		paramSets := []map[string]string{nil}
+		if names := routeParams(page.Route); len(names) > 0 {
+			sets, err := prerenderer.StaticParams(doc)
+			if err != nil {
+				return err
+			}
+			if len(sets) == 0 {
+				bs.ctx.Logger.Warn("Skipping dynamic route without staticParams",
+					core.StringField("route", page.Route),
+					core.StringField("path", page.RelPath))
+				continue
+			}
+			for _, set := range sets {
+				for _, name := range names {
+					if _, ok := set[name]; !ok {
+						return fmt.Errorf("%s: staticParams entry is missing %q", page.RelPath, name)
+					}
+				}
+			}
+			paramSets = sets
+		}
+
+		var critical string
+		if stylesheet != nil {
+			critical = criticalCSS(stylesheet, bs.routeClasses(page.AbsPath, asts))
+		}
+
+		for _, params := range paramSets {
+			html, err := prerenderer.RenderPage(doc, params)
+			if err != nil {
+				return fmt.Errorf("failed to prerender %s: %w", page.RelPath, err)
+			}
+			if bs.chunks != nil {
+				c, err := bs.chunks.chunkFor(page.AbsPath)
+				if err != nil {
+					return fmt.Errorf("failed to prerender %s: %w", page.RelPath, err)
+				}
+				html.Module = c.url
+				html.Preload = bs.chunks.preload[page.AbsPath]
+				html.Router = routerURL
+			}
+			switch {
+			case stylesheet != nil:
+				html.CriticalCSS = critical
+				html.DeferredStylesheets = append(html.DeferredStylesheets, "/tailwind.css")
+			case bs.ctx.BuildOptions.UsesTailwindCSS:
+				html.Stylesheets = append(html.Stylesheets, "/tailwind.css")
+			}
+			html.Head = append(html.Head, head...)
+
+			route := expandRoute(page.Route, params)
+			if policies != nil {
+				// The policy hashes the inline code of the page as rendered
+				// without it
+				policy := bs.routePolicy(page.AbsPath, html.Render(), outDir, asts)
+				policies[route] = policy
+				html.CSP = policy.String(true)
+			}
+			outPath := filepath.Join(outDir, filepath.FromSlash(strings.TrimPrefix(route, "/")), "index.html")
+			if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if err := os.WriteFile(outPath, []byte(html.Render()), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", outPath, err)
+			}
+			rendered++
+			sitemap = append(sitemap, sitemapRoute{Path: route, NoIndex: html.NoIndex})
+
+			bs.ctx.Logger.Debug("Prerendered route",
+				core.StringField("route", route),
+				core.StringField("file", outPath))
+		}
+	}
+
+	if policies != nil {
+		if err := writeCSPFiles(outDir, policies); err != nil {
+			return err
+		}
+	}
+	if base := bs.ctx.ProjectConfig.Sitemap.BaseURL; base != "" {
+		if err := writeSitemap(outDir, base, sitemap); err != nil {
+			return err
+		}
+	}
+
+	bs.ctx.Logger.Info("Prerendering completed", core.IntField("routes", rendered))
+
+	return nil
+}
+
+// routeForPage derives the route of a page from its location in the app
+// directory: app/blog/[slug].jml is served at /blog/:slug.
+func (bs *BuildSystem) routeForPage(path string) string {
+	rel, err := filepath.Rel(bs.ctx.Paths.AppDir, path)
+	if err != nil {
+		return ""
+	}
+	rel = strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))
+
+	var segments []string
+	for _, seg := range strings.Split(rel, "/") {
+		if strings.HasPrefix(seg, "[") && strings.HasSuffix(seg, "]") {
+			seg = ":" + seg[1:len(seg)-1]
+		}
+		segments = append(segments, seg)
+	}
+	if n := len(segments); n > 0 && segments[n-1] == "index" {
+		segments = segments[:n-1]
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// routeParams returns the names of the dynamic segments of a route.
+func routeParams(route string) []string {
+	var names []string
+	for _, seg := range strings.Split(route, "/") {
+		if strings.HasPrefix(seg, ":") {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+// expandRoute substitutes params into the dynamic segments of a route.
+func expandRoute(route string, params map[string]string) string {
+	segments := strings.Split(route, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			segments[i] = url.PathEscape(params[seg[1:]])
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,280 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+// vendorPackage is a runtime dependency of the emitted modules. Packages are
+// copied into the output and resolved in the browser through an import map.
+type vendorPackage struct {
+	name  string
+	entry string // module imported by the bare package name, if any
+}
+
+var vendorPackages = []vendorPackage{
+	{name: "lit", entry: "index.js"},
+	{name: "lit-html", entry: "lit-html.js"},
+	{name: "lit-element", entry: "index.js"},
+	{name: "@lit/reactive-element", entry: "reactive-element.js"},
+	{name: "@lit-labs/ssr-client"},
+	// Helpers such as __decorate, which tsc imports rather than inlines
+	// because importHelpers is on
+	{name: "tslib", entry: "tslib.es6.mjs"},
+}
+
+const vendorDir = "vendor"
+
+// BuildProject produces a production build of the project in outDir, which
+// defaults to the configured dist directory. The result is self-contained:
+// compiled modules, CSS, fingerprinted assets, runtime dependencies and
+// prerendered pages.
+func BuildProject(ctx *core.JawtContext, outDir string) error {
+	outDir, err := resolveOutputDir(ctx, outDir)
+	if err != nil {
+		return err
+	}
+
+	if err := runBuildScripts(ctx, "preBuild", ctx.ProjectConfig.GetPreBuildScripts(), outDir); err != nil {
+		return err
+	}
+
+	ctx.BuildOptions.Minify = ctx.ProjectConfig.Build.Minify && ctx.JawtConfig.EnableMinification
+	// Minified output only keeps its source maps when asked to
+	ctx.BuildOptions.SourceMaps = !ctx.BuildOptions.Minify || (ctx.ProjectConfig.SourceMaps && ctx.JawtConfig.EnableSourceMaps)
+	// Compiled apart from the dev build, which uses other options, and from
+	// scratch, so that nothing left over from deleted sources is shipped
+	ctx.Paths.SetBuildDir(filepath.Join(ctx.Paths.JawtDir, "build-production"))
+	if err := os.RemoveAll(ctx.Paths.BuildDir); err != nil {
+		return fmt.Errorf("failed to clean build directory: %w", err)
+	}
+
+	ctx.Logger.Info("Building project",
+		core.StringField("name", ctx.ProjectConfig.App.Name),
+		core.StringField("output", outDir),
+		core.StringField("mode", ctx.BuildOptions.Mode),
+		core.BoolField("minify", ctx.BuildOptions.Minify),
+		core.BoolField("sourceMaps", ctx.BuildOptions.SourceMaps))
+
+	// Assets are fingerprinted first so that documents can refer to them by
+	// their final URLs.
+	assets := newAssetPipeline(ctx)
+	if err := assets.Process(); err != nil {
+		return err
+	}
+
+	buildSystem := NewBuildSystem(ctx, nil)
+	buildSystem.assets = assets
+	if err := buildSystem.Build(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(outDir); err != nil {
+		return fmt.Errorf("failed to clean output directory: %w", err)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Compiled modules and CSS
+	if err := copyTree(ctx.Paths.BuildDir, outDir, func(rel string) bool {
+		if !ctx.BuildOptions.SourceMaps && strings.HasSuffix(rel, ".map") {
+			return true
+		}
+		return strings.HasSuffix(rel, ".tsbuildinfo")
+	}); err != nil {
+		return fmt.Errorf("failed to copy build output: %w", err)
+	}
+
+	if err := assets.Write(outDir); err != nil {
+		return err
+	}
+
+	imports, err := copyVendorPackages(ctx, filepath.Join(outDir, vendorDir))
+	if err != nil {
+		return err
+	}
+	// Builtin modules, like the env module, for scripts that import them
+	// by their alias
+	builtins, err := builtinImports(ctx.Paths.InternalSrcDir)
+	if err != nil {
+		return err
+	}
+	for spec, url := range builtins {
+		imports[spec] = url
+	}
+	importMap, err := importMapScript(imports)
+	if err != nil {
+		return err
+	}
+
+	if err := buildSystem.splitChunks(outDir, imports); err != nil {
+		return err
+	}
+	if ctx.BuildOptions.Minify {
+		if err := minifyModules(ctx, outDir); err != nil {
+			return err
+		}
+	}
+
+	head := []string{importMap}
+	if ctx.ProjectConfig.PWA.Enabled {
+		if err := writeWebManifest(ctx, assets, outDir); err != nil {
+			return err
+		}
+		head = append(head, pwaHead(ctx)...)
+	}
+
+	if err := buildSystem.Prerender(outDir, head...); err != nil {
+		return err
+	}
+
+	report, err := buildSystem.BundleReport(outDir, imports)
+	if err != nil {
+		return err
+	}
+	if ctx.ProjectConfig.PWA.Enabled {
+		if err := writeServiceWorker(ctx, outDir, report, assets, buildSystem.chunks != nil); err != nil {
+			return err
+		}
+	}
+	if err := writeBundleReport(ctx, report); err != nil {
+		return err
+	}
+	writeBundleTable(os.Stdout, report)
+	if err := checkBudgets(ctx, report); err != nil {
+		return err
+	}
+
+	if err := runBuildScripts(ctx, "postBuild", ctx.ProjectConfig.GetPostBuildScripts(), outDir); err != nil {
+		return err
+	}
+
+	ctx.Logger.Info("Build completed", core.StringField("output", outDir))
+
+	return nil
+}
+
+// resolveOutputDir returns the absolute output directory of a build, the
+// configured dist directory if none is given.
+func resolveOutputDir(ctx *core.JawtContext, outDir string) (string, error) {
+	if outDir == "" {
+		outDir = ctx.Paths.DistDir
+	}
+	outDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	// The output directory is wiped, so never let it contain the project.
+	if _, inside := within(outDir, ctx.Paths.ProjectRoot); inside {
+		return "", fmt.Errorf("output directory %s must not contain the project", outDir)
+	}
+
+	return outDir, nil
+}
+
+// copyVendorPackages copies the runtime dependencies from the managed
+// node_modules and returns the import map entries that resolve them.
+func copyVendorPackages(ctx *core.JawtContext, dest string) (map[string]string, error) {
+	imports := make(map[string]string)
+
+	for _, pkg := range vendorPackages {
+		src := filepath.Join(ctx.Paths.NodeModulesDir, filepath.FromSlash(pkg.name))
+		if _, err := os.Stat(src); err != nil {
+			return nil, fmt.Errorf("runtime dependency %s not found in %s: %w", pkg.name, ctx.Paths.NodeModulesDir, err)
+		}
+
+		if err := copyTree(src, filepath.Join(dest, filepath.FromSlash(pkg.name)), func(rel string) bool {
+			return strings.HasPrefix(rel, "node_modules") || strings.HasSuffix(rel, ".d.ts") || strings.HasSuffix(rel, ".map")
+		}); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", pkg.name, err)
+		}
+
+		url := "/" + vendorDir + "/" + pkg.name + "/"
+		imports[pkg.name+"/"] = url
+		if pkg.entry != "" {
+			imports[pkg.name] = url + pkg.entry
+		}
+	}
+
+	return imports, nil
+}
+
+// builtinImports returns the import map entries of the builtin modules in
+// the workspace, e.g. "@jawt/env" for /internal/env.js. tsc leaves the
+// specifiers as written and browsers don't add extensions, so every module
+// needs an entry of its own.
+func builtinImports(internalSrcDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(internalSrcDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list builtin modules: %w", err)
+	}
+
+	imports := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, ".d.ts") || filepath.Ext(name) != ".ts" {
+			continue
+		}
+		name = strings.TrimSuffix(name, ".ts")
+		imports["@jawt/"+name] = "/internal/" + name + ".js"
+	}
+	return imports, nil
+}
+
+// importMapScript returns the import map script for the given entries.
+func importMapScript(imports map[string]string) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{"imports": imports})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode import map: %w", err)
+	}
+
+	return `<script type="importmap">` + string(data) + `</script>`, nil
+}
+
+// copyTree copies every file under src to the same relative path under dst.
+// Files for which skip returns true are left out.
+func copyTree(src, dst string, skip func(rel string) bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if skip != nil && rel != "." && skip(filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// within returns path relative to dir if path is dir or inside it.
+func within(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,37 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuiltinImports(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"env.ts", "browser.ts", "types.d.ts"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	imports, err := builtinImports(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Browsers resolve "@jawt/env" exactly as mapped, so the URL must name
+	// the compiled file
+	expected := map[string]string{
+		"@jawt/env":     "/internal/env.js",
+		"@jawt/browser": "/internal/browser.js",
+	}
+	if !reflect.DeepEqual(imports, expected) {
+		t.Errorf("Expected %v, got %v", expected, imports)
+	}
+
+	m := &bundleMeasurer{imports: imports}
+	if got := m.resolve("@jawt/env", "/user/api.js"); got != "/internal/env.js" {
+		t.Errorf("Expected @jawt/env to resolve to /internal/env.js, got %q", got)
+	}
+}
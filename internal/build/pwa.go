@@ -0,0 +1,333 @@
+package build
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+const (
+	webManifestName   = "manifest.webmanifest"
+	serviceWorkerName = "sw.js"
+)
+
+// webManifest is the web app manifest that makes the app installable.
+type webManifest struct {
+	Name            string            `json:"name"`
+	ShortName       string            `json:"short_name,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	Version         string            `json:"version,omitempty"`
+	StartURL        string            `json:"start_url"`
+	Scope           string            `json:"scope"`
+	Display         string            `json:"display"`
+	ThemeColor      string            `json:"theme_color,omitempty"`
+	BackgroundColor string            `json:"background_color,omitempty"`
+	Icons           []webManifestIcon `json:"icons"`
+}
+
+type webManifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// writeWebManifest writes the web app manifest to outDir. Icons refer to
+// files in the assets directory and are written with their fingerprinted
+// URLs; their sizes and types are read from the files if not configured.
+func writeWebManifest(ctx *core.JawtContext, assets *assetPipeline, outDir string) error {
+	app := ctx.ProjectConfig.App
+	pwa := ctx.ProjectConfig.PWA
+
+	manifest := webManifest{
+		Name:            app.Name,
+		ShortName:       pwa.ShortName,
+		Description:     app.Description,
+		Version:         app.Version,
+		StartURL:        pwa.StartURL,
+		Scope:           "/",
+		Display:         pwa.Display,
+		ThemeColor:      pwa.ThemeColor,
+		BackgroundColor: pwa.BackgroundColor,
+		Icons:           []webManifestIcon{},
+	}
+
+	for _, icon := range pwa.Icons {
+		f, ok := assets.lookupFile(icon.Src)
+		if !ok {
+			return fmt.Errorf("PWA icon %s is not in the assets directory", icon.Src)
+		}
+
+		sizes := icon.Sizes
+		if sizes == "" {
+			sizes = assets.iconSizes(f)
+		}
+		typ := icon.Type
+		if typ == "" {
+			typ = mime.TypeByExtension(strings.ToLower(path.Ext(f.rel)))
+		}
+		manifest.Icons = append(manifest.Icons, webManifestIcon{Src: f.url, Sizes: sizes, Type: typ, Purpose: icon.Purpose})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode web manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, webManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write web manifest: %w", err)
+	}
+
+	return nil
+}
+
+// iconSizes returns the sizes of an icon as the manifest gives them.
+func (ap *assetPipeline) iconSizes(f *assetFile) string {
+	if strings.EqualFold(path.Ext(f.rel), ".svg") {
+		return "any"
+	}
+	if info, ok := ap.images[f.rel]; ok {
+		return fmt.Sprintf("%dx%d", info.Width, info.Height)
+	}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(f.content)); err == nil {
+		return fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)
+	}
+	return ""
+}
+
+// pwaHead returns the markup every page needs to link the manifest and
+// register the service worker.
+func pwaHead(ctx *core.JawtContext) []string {
+	head := []string{`<link rel="manifest" href="/` + webManifestName + `">`}
+	if color := ctx.ProjectConfig.PWA.ThemeColor; color != "" {
+		head = append(head, fmt.Sprintf(`<meta name="theme-color" content="%s">`, html.EscapeString(color)))
+	}
+	head = append(head, `<script type="module">if ('serviceWorker' in navigator) navigator.serviceWorker.register('/`+serviceWorkerName+`');</script>`)
+	return head
+}
+
+// precacheURLs returns what the service worker downloads when it is
+// installed: every prerendered page, everything the bundle report lists for
+// its routes, all fingerprinted assets and the manifest. When the build is
+// split into chunks, the router and the route manifest it reads before
+// every navigation are precached too.
+func precacheURLs(outDir string, report *BundleReport, assets *assetPipeline, chunked bool) ([]string, error) {
+	set := map[string]bool{"/" + webManifestName: true}
+	if chunked {
+		set[routerURL] = true
+		set["/"+routeManifestName] = true
+	}
+
+	err := filepath.Walk(outDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && info.Name() == "index.html" {
+			rel, err := filepath.Rel(outDir, p)
+			if err != nil {
+				return err
+			}
+			set["/"+filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prerendered pages: %w", err)
+	}
+
+	for _, route := range report.Routes {
+		for _, f := range route.Files {
+			set[f.Path] = true
+		}
+	}
+	for _, f := range assets.files {
+		set[f.url] = true
+	}
+	for _, f := range assets.variants {
+		set[f.url] = true
+	}
+
+	urls := make([]string, 0, len(set))
+	for url := range set {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// buildVersion hashes the precached files, so that the service worker's
+// caches change whenever anything it serves does.
+func buildVersion(outDir string, urls []string) (string, error) {
+	h := sha256.New()
+	for _, url := range urls {
+		content, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(strings.TrimPrefix(url, "/"))))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", url, err)
+		}
+		sum := sha256.Sum256(content)
+		fmt.Fprintf(h, "%s %x\n", url, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:10], nil
+}
+
+var cacheNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// writeServiceWorker writes a service worker that precaches the build and
+// handles other requests with the configured runtime caching strategy.
+func writeServiceWorker(ctx *core.JawtContext, outDir string, report *BundleReport, assets *assetPipeline, chunked bool) error {
+	urls, err := precacheURLs(outDir, report, assets, chunked)
+	if err != nil {
+		return err
+	}
+	version, err := buildVersion(outDir, urls)
+	if err != nil {
+		return err
+	}
+
+	name := strings.Trim(cacheNameUnsafe.ReplaceAllString(strings.ToLower(ctx.ProjectConfig.App.Name), "-"), "-")
+	if name == "" {
+		name = "app"
+	}
+	prefix := "jawt-" + name + "-"
+	script, err := serviceWorkerScript(prefix, version, ctx.ProjectConfig.PWA.RuntimeCaching, urls)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, serviceWorkerName), []byte(script), 0644); err != nil {
+		return fmt.Errorf("failed to write service worker: %w", err)
+	}
+
+	ctx.Logger.Info("Generated service worker",
+		core.StringField("version", version),
+		core.IntField("precached", len(urls)),
+		core.StringField("runtimeCaching", ctx.ProjectConfig.PWA.RuntimeCaching))
+
+	return nil
+}
+
+func serviceWorkerScript(prefix, version, strategy string, urls []string) (string, error) {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	if urls == nil {
+		urls = []string{}
+	}
+
+	strategies := map[string]string{
+		"network-first":          "networkFirst",
+		"cache-first":            "cacheFirst",
+		"stale-while-revalidate": "staleWhileRevalidate",
+	}
+	fn, ok := strategies[strategy]
+	if !ok {
+		return "", fmt.Errorf("unknown runtime caching strategy %q", strategy)
+	}
+
+	return fmt.Sprintf(serviceWorkerTemplate, encode(prefix), encode(version), encode(urls), fn), nil
+}
+
+// serviceWorkerTemplate is filled in with the cache name prefix, the build
+// version, the precached URLs and the runtime caching function.
+const serviceWorkerTemplate = `// Generated by jawt build. Do not edit.
+const CACHE_PREFIX = %s;
+const VERSION = %s;
+const PRECACHE = CACHE_PREFIX + 'precache-' + VERSION;
+const RUNTIME = CACHE_PREFIX + 'runtime-' + VERSION;
+const PRECACHE_URLS = %s;
+
+self.addEventListener('install', (event) => {
+  event.waitUntil(
+    caches.open(PRECACHE)
+      .then((cache) => cache.addAll(PRECACHE_URLS))
+      .then(() => self.skipWaiting())
+  );
+});
+
+// Caches of earlier builds are removed once this one takes over
+self.addEventListener('activate', (event) => {
+  event.waitUntil(
+    caches.keys()
+      .then((keys) => Promise.all(keys
+        .filter((key) => key.startsWith(CACHE_PREFIX) && key !== PRECACHE && key !== RUNTIME)
+        .map((key) => caches.delete(key))))
+      .then(() => self.clients.claim())
+  );
+});
+
+function store(cache, request, response) {
+  if (response.ok) {
+    cache.put(request, response.clone());
+  }
+  return response;
+}
+
+async function networkFirst(request) {
+  const cache = await caches.open(RUNTIME);
+  try {
+    return store(cache, request, await fetch(request));
+  } catch (err) {
+    const cached = await cache.match(request);
+    if (cached) {
+      return cached;
+    }
+    throw err;
+  }
+}
+
+async function cacheFirst(request) {
+  const cache = await caches.open(RUNTIME);
+  const cached = await cache.match(request);
+  return cached || store(cache, request, await fetch(request));
+}
+
+async function staleWhileRevalidate(request, event) {
+  const cache = await caches.open(RUNTIME);
+  const cached = await cache.match(request);
+  const fetched = fetch(request).then((response) => store(cache, request, response));
+  if (cached) {
+    event.waitUntil(fetched.catch(() => {}));
+    return cached;
+  }
+  return fetched;
+}
+
+// Pages are prerendered to <route>/index.html
+function pageURL(url) {
+  const path = url.pathname.endsWith('/') ? url.pathname : url.pathname + '/';
+  return path + 'index.html';
+}
+
+self.addEventListener('fetch', (event) => {
+  const request = event.request;
+  const url = new URL(request.url);
+  if (request.method !== 'GET' || url.origin !== self.location.origin) {
+    return;
+  }
+
+  if (request.mode === 'navigate') {
+    event.respondWith(
+      fetch(request).catch(async () =>
+        (await caches.match(pageURL(url), { cacheName: PRECACHE })) || Response.error())
+    );
+    return;
+  }
+
+  event.respondWith(
+    caches.match(url.pathname, { cacheName: PRECACHE })
+      .then((cached) => cached || %s(request, event))
+  );
+});
+`
@@ -0,0 +1,70 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPrecacheURLs(t *testing.T) {
+	out := t.TempDir()
+	files := map[string]string{
+		"index.html":                 "<html></html>",
+		"blog/hello/index.html":      "<html></html>",
+		"chunks/page-index.js":       "import '/vendor/lit/index.js';",
+		"vendor/lit/index.js":        "export {};",
+		"tailwind.css":               ".flex{display:flex}",
+		"assets/logo.3f9a1c0b2e.png": "png",
+		"manifest.webmanifest":       "{}",
+		"route-manifest.json":        "{}",
+		"internal/router.js":         "export function start() {}",
+	}
+	writeTree(t, out, files)
+
+	report := &BundleReport{Routes: []RouteBundle{{
+		Route: "/",
+		Files: []BundleFile{
+			{Path: "/chunks/page-index.js"},
+			{Path: "/vendor/lit/index.js"},
+			{Path: "/tailwind.css"},
+		},
+	}}}
+	assets := &assetPipeline{files: map[string]*assetFile{
+		"assets/logo.png": {rel: "assets/logo.png", url: "/assets/logo.3f9a1c0b2e.png"},
+	}}
+
+	urls, err := precacheURLs(out, report, assets, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"/assets/logo.3f9a1c0b2e.png",
+		"/blog/hello/index.html",
+		"/chunks/page-index.js",
+		"/index.html",
+		"/internal/router.js",
+		"/manifest.webmanifest",
+		"/route-manifest.json",
+		"/tailwind.css",
+		"/vendor/lit/index.js",
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected precached URLs %v, got %v", expected, urls)
+	}
+
+	before, err := buildVersion(out, urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "tailwind.css"), []byte(".grid{display:grid}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	after, err := buildVersion(out, urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Errorf("Expected the version to change with the output, got %s both times", before)
+	}
+}
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+// ImportResolver maps the specifiers of component and script imports to
+// the files they refer to.
+type ImportResolver struct {
+	root string
+}
+
+func NewImportResolver(projectRoot string) *ImportResolver {
+	return &ImportResolver{root: projectRoot}
+}
+
+// Resolve returns the absolute path of the file imp refers to, for an
+// import in the document at from. Specifiers are tried relative to the
+// importing document first and then relative to the project root, so
+// "widget" next to the document and "components/layout" both work, and a
+// file next to the document shadows one at the same path from the root.
+// Component imports may leave out .jml and script imports .ts or .tsx.
+func (r *ImportResolver) Resolve(imp *ast.Import, from string) (string, bool) {
+	var exts []string
+	switch imp.Kind {
+	case ast.ImportComponent:
+		exts = []string{".jml"}
+	case ast.ImportScript:
+		exts = []string{".ts", ".tsx"}
+	default:
+		return "", false
+	}
+
+	spec := filepath.FromSlash(imp.Path)
+	bases := []string{
+		filepath.Join(filepath.Dir(from), spec),
+		filepath.Join(r.root, spec),
+	}
+	for _, base := range bases {
+		if resolved := resolveWithExtensions(base, exts); resolved != "" {
+			if abs, err := filepath.Abs(resolved); err == nil {
+				resolved = abs
+			}
+			return filepath.Clean(resolved), true
+		}
+	}
+	return "", false
+}
+
+// ResolveDocument fills in the ResolvedPath of every component and script
+// import in doc and reports the ones that can't be resolved.
+func (r *ImportResolver) ResolveDocument(doc *ast.Document, reporter *diagnostic.Reporter) {
+	for _, imp := range doc.Imports {
+		if imp.Kind == ast.ImportBuiltin {
+			continue
+		}
+
+		resolved, ok := r.Resolve(imp, doc.SourceFile)
+		if !ok {
+			reporter.Add(diagnostic.NewDiagnostic("UNRESOLVED_IMPORT",
+				fmt.Sprintf("cannot find %s %q imported as %s", imp.Kind, imp.Path, imp.Name),
+				diagnostic.Position{Line: imp.Line, Column: imp.Column, File: imp.File},
+				diagnostic.SeverityError, "resolver"))
+			continue
+		}
+		imp.ResolvedPath = resolved
+	}
+}
+
+func resolveWithExtensions(base string, exts []string) string {
+	candidates := []string{base}
+	for _, ext := range exts {
+		candidates = append(candidates, base+ext)
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
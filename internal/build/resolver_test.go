@@ -0,0 +1,87 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+func TestImportResolver(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"components/layout.jml",
+		"components/widget.jml",
+		"app/widget.jml",
+		"scripts/analytics.ts",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page := filepath.Join(root, "app", "index.jml")
+	doc := &ast.Document{
+		SourceFile: page,
+		Imports: []*ast.Import{
+			{Kind: ast.ImportComponent, Name: "Layout", Path: "components/layout"},
+			{Kind: ast.ImportComponent, Name: "Widget", Path: "widget"},
+			{Kind: ast.ImportScript, Name: "analytics", Path: "scripts/analytics"},
+			{Kind: ast.ImportBuiltin, Name: "browser"},
+			{Position: ast.Position{Line: 6, Column: 1, File: page}, Kind: ast.ImportComponent, Name: "Missing", Path: "components/missing"},
+		},
+	}
+
+	reporter := diagnostic.NewReporter()
+	NewImportResolver(root).ResolveDocument(doc, reporter)
+
+	expected := []string{
+		filepath.Join(root, "components", "layout.jml"),
+		filepath.Join(root, "app", "widget.jml"),
+		filepath.Join(root, "scripts", "analytics.ts"),
+		"",
+		"",
+	}
+	for i, imp := range doc.Imports {
+		if imp.ResolvedPath != expected[i] {
+			t.Errorf("Import %q: expected %q, got %q", imp.Path, expected[i], imp.ResolvedPath)
+		}
+	}
+
+	errs := reporter.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d", len(errs))
+	}
+	if errs[0].Code != "UNRESOLVED_IMPORT" || errs[0].Pos.Line != 6 {
+		t.Errorf("Unexpected diagnostic: %+v", errs[0])
+	}
+}
+
+func TestImportResolverPrefersDocumentDirectory(t *testing.T) {
+	// Both app/components/header.jml and components/header.jml match
+	// "components/header" from app/index.jml
+	root := t.TempDir()
+	for _, file := range []string{"components/header.jml", "app/components/header.jml"} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	imp := &ast.Import{Kind: ast.ImportComponent, Name: "Header", Path: "components/header"}
+	resolved, ok := NewImportResolver(root).Resolve(imp, filepath.Join(root, "app", "index.jml"))
+
+	expected := filepath.Join(root, "app", "components", "header.jml")
+	if !ok || resolved != expected {
+		t.Errorf("Expected %q, got %q", expected, resolved)
+	}
+}
@@ -0,0 +1,169 @@
+package build
+
+// routerModuleName is the builtin module that navigates between the routes
+// of a production build without reloading the page. The boot script of
+// every prerendered page starts it once the page module is loaded.
+const routerModuleName = "router.ts"
+
+// routerURL is where the compiled router is served from.
+const routerURL = "/internal/router.js"
+
+// routerModule reads the route manifest written by splitChunks. On a
+// navigation it preloads the chunk of the route and the modules it imports,
+// loads it, and swaps a new page element in for the current one. Anything
+// it can't handle falls back to a full page load.
+const routerModule = `// Generated by jawt. Client-side navigation between prerendered routes.
+
+interface RouteEntry {
+  page: string;
+  tag: string;
+  chunk: string;
+  preload: string[];
+}
+
+interface RouteMatch {
+  entry: RouteEntry;
+  params: Record<string, string>;
+}
+
+type PageElement = HTMLElement & { params?: Record<string, string> };
+
+let manifest: Promise<Record<string, RouteEntry>> | undefined;
+let current: PageElement | null = null;
+const preloaded = new Set<string>();
+
+function loadManifest(): Promise<Record<string, RouteEntry>> {
+  if (!manifest) {
+    manifest = fetch("/route-manifest.json").then((response) => {
+      if (!response.ok) {
+        throw new Error("route manifest: " + response.status);
+      }
+      return response.json() as Promise<Record<string, RouteEntry>>;
+    });
+  }
+  return manifest;
+}
+
+function trimSlash(path: string): string {
+  return path.length > 1 && path.endsWith("/") ? path.slice(0, -1) : path;
+}
+
+// Static routes win over dynamic ones
+function match(routes: Record<string, RouteEntry>, pathname: string): RouteMatch | null {
+  const path = trimSlash(pathname);
+  const exact = routes[path];
+  if (exact) {
+    return { entry: exact, params: {} };
+  }
+  const segments = path.split("/");
+  for (const route of Object.keys(routes).sort()) {
+    const pattern = route.split("/");
+    if (pattern.length !== segments.length || !route.includes("/:")) {
+      continue;
+    }
+    const params: Record<string, string> = {};
+    let ok = true;
+    for (let i = 0; i < pattern.length && ok; i++) {
+      if (pattern[i].startsWith(":")) {
+        try {
+          params[pattern[i].slice(1)] = decodeURIComponent(segments[i]);
+        } catch {
+          ok = false;
+        }
+      } else {
+        ok = pattern[i] === segments[i];
+      }
+    }
+    if (ok) {
+      return { entry: routes[route], params };
+    }
+  }
+  return null;
+}
+
+function preload(entry: RouteEntry): void {
+  for (const href of [entry.chunk, ...entry.preload]) {
+    if (preloaded.has(href)) {
+      continue;
+    }
+    preloaded.add(href);
+    const link = document.createElement("link");
+    link.rel = "modulepreload";
+    link.href = href;
+    document.head.append(link);
+  }
+}
+
+// The title may depend on the params, so it is taken from the prerendered page
+async function pageTitle(url: URL): Promise<string | null> {
+  const response = await fetch(url.pathname);
+  if (!response.ok) {
+    return null;
+  }
+  const doc = new DOMParser().parseFromString(await response.text(), "text/html");
+  return doc.title;
+}
+
+async function navigate(url: URL, push: boolean): Promise<void> {
+  try {
+    const found = match(await loadManifest(), url.pathname);
+    if (!found || !current) {
+      throw new Error("no route for " + url.pathname);
+    }
+    preload(found.entry);
+    const [, title] = await Promise.all([import(found.entry.chunk), pageTitle(url)]);
+
+    const page = document.createElement(found.entry.tag) as PageElement;
+    page.params = found.params;
+    current.replaceWith(page);
+    current = page;
+    if (title !== null) {
+      document.title = title;
+    }
+    if (push) {
+      history.pushState(null, "", url.href);
+    }
+    if (url.hash) {
+      document.getElementById(decodeURIComponent(url.hash.slice(1)))?.scrollIntoView();
+    } else {
+      window.scrollTo(0, 0);
+    }
+  } catch {
+    location.assign(url.href);
+  }
+}
+
+function onClick(event: MouseEvent): void {
+  if (event.defaultPrevented || event.button !== 0 || event.metaKey || event.ctrlKey || event.shiftKey || event.altKey) {
+    return;
+  }
+  // Links inside shadow roots are found through the composed path
+  const anchor = event.composedPath().find((node): node is HTMLAnchorElement => node instanceof HTMLAnchorElement);
+  if (!anchor || !anchor.href || (anchor.target && anchor.target !== "_self") || anchor.hasAttribute("download")) {
+    return;
+  }
+  const url = new URL(anchor.href);
+  if (url.origin !== location.origin) {
+    return;
+  }
+  if (url.pathname === location.pathname && url.search === location.search && url.hash) {
+    return;
+  }
+  event.preventDefault();
+  void navigate(url, true);
+}
+
+// start takes over navigation from the page element with the given tag.
+export function start(tag: string): void {
+  if (current) {
+    return;
+  }
+  current = document.querySelector<PageElement>(tag);
+  document.addEventListener("click", onClick);
+  window.addEventListener("popstate", () => {
+    void navigate(new URL(location.href), false);
+  });
+  // Routes are usually visited soon after the page has loaded
+  void loadManifest().catch(() => undefined);
+}
+`
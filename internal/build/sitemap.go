@@ -0,0 +1,63 @@
+package build
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sitemapName = "sitemap.xml"
+	robotsName  = "robots.txt"
+)
+
+// sitemapRoute is a prerendered route. Dynamic routes appear once for every
+// set of staticParams.
+type sitemapRoute struct {
+	Path    string
+	NoIndex bool
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// writeSitemap writes sitemap.xml, listing every route that may be indexed,
+// and robots.txt, which points crawlers to the sitemap. Routes are made
+// absolute with baseURL. noindex routes are only left out of the sitemap:
+// a crawler that robots.txt kept away would never see their noindex tag.
+func writeSitemap(outDir, baseURL string, routes []sitemapRoute) error {
+	base := strings.TrimRight(baseURL, "/")
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: []sitemapURL{}}
+	for _, route := range routes {
+		if !route.NoIndex {
+			set.URLs = append(set.URLs, sitemapURL{Loc: base + route.Path})
+		}
+	}
+	robots := fmt.Sprintf("User-agent: *\nAllow: /\n\nSitemap: %s/%s\n", base, sitemapName)
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sitemap: %w", err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	if err := os.WriteFile(filepath.Join(outDir, sitemapName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write sitemap: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, robotsName), []byte(robots), 0644); err != nil {
+		return fmt.Errorf("failed to write robots.txt: %w", err)
+	}
+
+	return nil
+}
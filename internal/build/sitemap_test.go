@@ -0,0 +1,50 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSitemap(t *testing.T) {
+	out := t.TempDir()
+	routes := []sitemapRoute{
+		{Path: "/blog/hello-world"},
+		{Path: "/"},
+		{Path: "/drafts", NoIndex: true},
+		{Path: "/tags/rock&roll"},
+	}
+	if err := writeSitemap(out, "https://example.com/", routes); err != nil {
+		t.Fatal(err)
+	}
+
+	sitemap, err := os.ReadFile(filepath.Join(out, sitemapName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+  </url>
+  <url>
+    <loc>https://example.com/blog/hello-world</loc>
+  </url>
+  <url>
+    <loc>https://example.com/tags/rock&amp;roll</loc>
+  </url>
+</urlset>
+`
+	if string(sitemap) != expected {
+		t.Errorf("Expected sitemap:\n%s\ngot:\n%s", expected, sitemap)
+	}
+
+	robots, err := os.ReadFile(filepath.Join(out, robotsName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "User-agent: *\nAllow: /\n\nSitemap: https://example.com/sitemap.xml\n"
+	if string(robots) != expected {
+		t.Errorf("Expected robots.txt:\n%s\ngot:\n%s", expected, robots)
+	}
+}
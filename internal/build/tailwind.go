@@ -0,0 +1,81 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+// classManifestName is the file in the generated directory listing every
+// class used by the project. Tailwind scans it instead of the emitted code.
+const classManifestName = "tailwind-classes.txt"
+
+// tailwindInput is the stylesheet Tailwind expands into the project's CSS.
+const tailwindInput = `@tailwind base;
+@tailwind components;
+@tailwind utilities;
+`
+
+func tailwindInputPath(paths *core.ProjectPaths) string {
+	return filepath.Join(paths.GeneratedDir, "tailwind.input.css")
+}
+
+func (bs *BuildSystem) classManifestPath() string {
+	return filepath.Join(bs.ctx.Paths.GeneratedDir, classManifestName)
+}
+
+// setClasses records the classes used by a document.
+func (bs *BuildSystem) setClasses(path string, classes []string) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if len(classes) == 0 {
+		delete(bs.classes, path)
+		return
+	}
+	bs.classes[path] = classes
+}
+
+// writeClassManifest writes the classes used across the project to the
+// generated class manifest and enables Tailwind when any are used. It
+// reports whether the manifest changed.
+func (bs *BuildSystem) writeClassManifest() (bool, error) {
+	bs.mu.Lock()
+	seen := make(map[string]bool)
+	for _, classes := range bs.classes {
+		for _, class := range classes {
+			seen[class] = true
+		}
+	}
+	bs.ctx.BuildOptions.UsesTailwindCSS = len(seen) > 0
+	bs.mu.Unlock()
+
+	all := make([]string, 0, len(seen))
+	for class := range seen {
+		all = append(all, class)
+	}
+	sort.Strings(all)
+	content := strings.Join(all, "\n") + "\n"
+
+	path := bs.classManifestPath()
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create generated directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write class manifest: %w", err)
+	}
+
+	bs.ctx.Logger.Debug("Class manifest updated",
+		core.IntField("classes", len(all)),
+		core.StringField("path", path))
+
+	return true, nil
+}
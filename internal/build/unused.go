@@ -0,0 +1,322 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+// UnusedReport lists the components no page uses and the scripts nothing
+// used imports. Paths are absolute and sorted.
+type UnusedReport struct {
+	Components []string
+	Scripts    []string
+}
+
+// Empty reports whether nothing unused was found.
+func (r *UnusedReport) Empty() bool {
+	return len(r.Components) == 0 && len(r.Scripts) == 0
+}
+
+// Files returns every unused file, components first.
+func (r *UnusedReport) Files() []string {
+	return append(append([]string{}, r.Components...), r.Scripts...)
+}
+
+// FindUnused discovers the project and reports its unused components and
+// scripts. Every document must parse, since an import in a document that
+// can't be read would otherwise go unseen.
+func FindUnused(ctx *core.JawtContext) (*UnusedReport, error) {
+	bs := NewBuildSystem(ctx, nil)
+	if err := bs.DiscoverProject(); err != nil {
+		return nil, err
+	}
+
+	// The documents were parsed for the graph already
+	docs := make(map[string]*ast.Document, len(bs.docs))
+	for path := range bs.docs {
+		parsed, err := bs.takeParsed(path)
+		if err == nil && parsed.reporter.HasErrors() {
+			diagnostic.NewPrinter().Print(parsed.reporter)
+			err = fmt.Errorf("%d errors", len(parsed.reporter.Errors()))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", bs.graphID(path), err)
+		}
+		bs.resolver.ResolveDocument(parsed.document, diagnostic.NewReporter())
+		docs[path] = parsed.document
+	}
+
+	return bs.findUnused(docs)
+}
+
+// findUnused works out what is unused from the parsed documents of the
+// project. Lazy imports count as uses, and so do imports of scripts that
+// are themselves used, but imports from unused components don't. The
+// exports of a library are used as well, and so are the scripts that are
+// run or loaded from outside JML.
+func (bs *BuildSystem) findUnused(docs map[string]*ast.Document) (*UnusedReport, error) {
+	var pages []string
+	for path, doc := range bs.docs {
+		if doc.Type == DocumentTypePage {
+			pages = append(pages, path)
+		}
+	}
+	exportedComponents, exportedScripts := bs.exportPaths()
+	pages = append(pages, exportedComponents...)
+
+	used, scripts := reachableFromPages(pages, docs)
+	for _, path := range append(exportedScripts, bs.scriptEntryPoints()...) {
+		scripts[path] = true
+	}
+	bs.followScriptImports(scripts)
+
+	report := &UnusedReport{}
+	for path, doc := range bs.docs {
+		if doc.Type == DocumentTypeComponent && !used[path] {
+			report.Components = append(report.Components, path)
+		}
+	}
+
+	all, err := bs.ctx.Paths.GetTypeScriptFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scripts: %w", err)
+	}
+	for _, path := range all {
+		if strings.HasSuffix(path, ".d.ts") {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if !scripts[path] {
+			report.Scripts = append(report.Scripts, path)
+		}
+	}
+
+	sort.Strings(report.Components)
+	sort.Strings(report.Scripts)
+
+	return report, nil
+}
+
+// htmlReferencePattern matches the URLs that HTML loads from.
+var htmlReferencePattern = regexp.MustCompile(`\b(?:src|href)\s*=\s*["']([^"']+)["']`)
+
+// scriptEntryPoints returns the scripts that JML doesn't import but that
+// are used anyway: those named in the preBuild and postBuild scripts, such
+// as "tsx scripts/seed.ts", and those that an HTML file in the project
+// loads.
+func (bs *BuildSystem) scriptEntryPoints() []string {
+	paths := bs.ctx.Paths
+	var entries []string
+	add := func(base string) {
+		base = strings.TrimSuffix(base, ".js")
+		resolved := resolveWithExtensions(base, []string{".ts", ".tsx"})
+		if resolved == "" {
+			return
+		}
+		if abs, err := filepath.Abs(resolved); err == nil {
+			resolved = abs
+		}
+		if _, ok := within(paths.ScriptsDir, resolved); ok {
+			entries = append(entries, resolved)
+		}
+	}
+
+	config := bs.ctx.ProjectConfig
+	for _, command := range append(append([]string{}, config.GetPreBuildScripts()...), config.GetPostBuildScripts()...) {
+		for _, arg := range strings.Fields(command) {
+			arg = strings.Trim(arg, `"'`)
+			if !filepath.IsAbs(arg) {
+				arg = filepath.Join(paths.ProjectRoot, filepath.FromSlash(arg))
+			}
+			add(arg)
+		}
+	}
+
+	// The workspace, dependencies and build output hold no sources
+	_ = filepath.Walk(paths.ProjectRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != paths.ProjectRoot && (strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules" || path == paths.DistDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".html") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		for _, m := range htmlReferencePattern.FindAllStringSubmatch(string(content), -1) {
+			ref := m[1]
+			if i := strings.IndexAny(ref, "?#"); i >= 0 {
+				ref = ref[:i]
+			}
+			if ref == "" || isExternalURL(ref) {
+				continue
+			}
+			if strings.HasPrefix(ref, "/") {
+				add(filepath.Join(paths.ProjectRoot, filepath.FromSlash(ref)))
+			} else {
+				add(filepath.Join(filepath.Dir(path), filepath.FromSlash(ref)))
+			}
+		}
+		return nil
+	})
+
+	return entries
+}
+
+// reachableFromPages follows the resolved component imports of docs from
+// the given pages. It returns the documents reached, pages included, and
+// the scripts they import.
+func reachableFromPages(pages []string, docs map[string]*ast.Document) (map[string]bool, map[string]bool) {
+	used := make(map[string]bool)
+	scripts := make(map[string]bool)
+
+	queue := append([]string{}, pages...)
+	for _, page := range pages {
+		used[page] = true
+	}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+
+		doc, ok := docs[path]
+		if !ok {
+			continue
+		}
+		for _, imp := range doc.Imports {
+			if imp.ResolvedPath == "" {
+				continue
+			}
+			switch imp.Kind {
+			case ast.ImportComponent:
+				if !used[imp.ResolvedPath] {
+					used[imp.ResolvedPath] = true
+					queue = append(queue, imp.ResolvedPath)
+				}
+			case ast.ImportScript:
+				scripts[imp.ResolvedPath] = true
+			}
+		}
+	}
+
+	return used, scripts
+}
+
+// followScriptImports adds the scripts that the scripts in the set import,
+// directly or through other scripts.
+func (bs *BuildSystem) followScriptImports(scripts map[string]bool) {
+	queue := make([]string, 0, len(scripts))
+	for path := range scripts {
+		queue = append(queue, path)
+	}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		for _, dep := range bs.scriptImports(path) {
+			if !scripts[dep] {
+				scripts[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+}
+
+// tsImportPattern matches the specifiers of static, dynamic and re-export
+// imports in TypeScript.
+var tsImportPattern = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*)['"]([^'"]+)['"]`)
+
+// scriptImports returns the scripts in the scripts directory that the
+// script at path imports. Relative specifiers and the "@/" alias for the
+// scripts directory are followed; packages are not.
+func (bs *BuildSystem) scriptImports(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var deps []string
+	for _, m := range tsImportPattern.FindAllStringSubmatch(string(content), -1) {
+		spec := m[1]
+		var base string
+		switch {
+		case strings.HasPrefix(spec, "./"), strings.HasPrefix(spec, "../"):
+			base = filepath.Join(filepath.Dir(path), filepath.FromSlash(spec))
+		case strings.HasPrefix(spec, "@/"):
+			base = filepath.Join(bs.ctx.Paths.ScriptsDir, filepath.FromSlash(spec[2:]))
+		default:
+			continue
+		}
+
+		// Compiled specifiers may name the .js file of a .ts source
+		base = strings.TrimSuffix(base, ".js")
+		resolved := resolveWithExtensions(base, []string{".ts", ".tsx"})
+		if resolved == "" {
+			resolved = resolveWithExtensions(filepath.Join(base, "index"), []string{".ts", ".tsx"})
+		}
+		if resolved == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(resolved); err == nil {
+			resolved = abs
+		}
+		deps = append(deps, resolved)
+	}
+	return deps
+}
+
+// lintUnused warns about unused components and scripts once the whole
+// project has compiled. It needs every document's AST, so it does nothing
+// if any document failed to compile.
+func (bs *BuildSystem) lintUnused() {
+	bs.mu.RLock()
+	docs := make(map[string]*ast.Document, len(bs.asts))
+	for path, doc := range bs.asts {
+		docs[path] = doc
+	}
+	complete := len(docs) == len(bs.docs)
+	bs.mu.RUnlock()
+
+	if !complete {
+		return
+	}
+
+	report, err := bs.findUnused(docs)
+	if err != nil {
+		bs.ctx.Logger.Warn("Failed to look for unused files", core.ErrorField(err))
+		return
+	}
+	if report.Empty() {
+		return
+	}
+
+	reporter := diagnostic.NewReporter()
+	for _, path := range report.Components {
+		reporter.Add(diagnostic.NewDiagnostic("UNUSED_COMPONENT",
+			fmt.Sprintf("component %s is not used by any page", bs.graphID(path)),
+			diagnostic.Position{Line: 1, Column: 1, File: path},
+			diagnostic.SeverityWarning, "lint"))
+	}
+	for _, path := range report.Scripts {
+		reporter.Add(diagnostic.NewDiagnostic("UNUSED_SCRIPT",
+			fmt.Sprintf("script %s is not imported by any page or component in use", bs.graphID(path)),
+			diagnostic.Position{Line: 1, Column: 1, File: path},
+			diagnostic.SeverityWarning, "lint"))
+	}
+	diagnostic.NewPrinter().Print(reporter)
+}
@@ -0,0 +1,94 @@
+package build
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestReachableFromPages(t *testing.T) {
+	// app/index uses layout, which lazily uses tree; tree uses itself.
+	// orphan uses button, but nothing uses orphan.
+	docs := map[string]*ast.Document{
+		"/p/app/index.jml": {Imports: []*ast.Import{
+			{Kind: ast.ImportComponent, ResolvedPath: "/p/components/layout.jml"},
+			{Kind: ast.ImportScript, ResolvedPath: "/p/scripts/analytics.ts"},
+		}},
+		"/p/components/layout.jml": {Imports: []*ast.Import{
+			{Kind: ast.ImportComponent, Lazy: true, ResolvedPath: "/p/components/tree.jml"},
+			{Kind: ast.ImportComponent, Path: "components/missing"},
+		}},
+		"/p/components/tree.jml": {Imports: []*ast.Import{
+			{Kind: ast.ImportComponent, Lazy: true, ResolvedPath: "/p/components/tree.jml"},
+		}},
+		"/p/components/orphan.jml": {Imports: []*ast.Import{
+			{Kind: ast.ImportComponent, ResolvedPath: "/p/components/button.jml"},
+			{Kind: ast.ImportScript, ResolvedPath: "/p/scripts/orphan.ts"},
+		}},
+		"/p/components/button.jml": {},
+	}
+
+	used, scripts := reachableFromPages([]string{"/p/app/index.jml"}, docs)
+
+	keys := func(m map[string]bool) []string {
+		var out []string
+		for k := range m {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	expectedUsed := []string{"/p/app/index.jml", "/p/components/layout.jml", "/p/components/tree.jml"}
+	if got := keys(used); !reflect.DeepEqual(got, expectedUsed) {
+		t.Errorf("Expected used documents %v, got %v", expectedUsed, got)
+	}
+
+	expectedScripts := []string{"/p/scripts/analytics.ts"}
+	if got := keys(scripts); !reflect.DeepEqual(got, expectedScripts) {
+		t.Errorf("Expected used scripts %v, got %v", expectedScripts, got)
+	}
+}
+
+func TestFindUnusedScriptEntryPoints(t *testing.T) {
+	// seed runs before the build and imports helper, and embed.html loads
+	// widget. Only dead is unused: the build output doesn't count.
+	root := t.TempDir()
+	config := core.DefaultProjectConfig()
+	config.Scripts.PreBuild = []string{"npx tsx scripts/seed.ts --fast"}
+	config.Build.DistDir = "dist"
+	paths, err := core.NewProjectPaths(root, config, core.DefaultJawtConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"scripts/seed.ts":   `import { pad } from "./helper";`,
+		"scripts/helper.ts": "",
+		"scripts/widget.ts": "",
+		"scripts/dead.ts":   "",
+		"assets/embed.html": `<script type="module" src="/scripts/widget.ts?v=2"></script>`,
+		"dist/index.html":   `<script type="module" src="/scripts/dead.ts"></script>`,
+	}
+	writeTree(t, root, files)
+
+	ctx := &core.JawtContext{
+		ProjectConfig: config,
+		Paths:         paths,
+		Logger:        core.NewDefaultLogger(core.ErrorLevel),
+		BuildOptions:  core.NewBuildOptions(),
+	}
+	report, err := NewBuildSystem(ctx, nil).findUnused(map[string]*ast.Document{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(paths.ScriptsDir, "dead.ts")}
+	if !reflect.DeepEqual(report.Scripts, expected) {
+		t.Errorf("Expected unused scripts %v, got %v", expected, report.Scripts)
+	}
+}
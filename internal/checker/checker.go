@@ -0,0 +1,87 @@
+package checker
+
+import (
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/diagnostic"
+)
+
+// Checker performs semantic checks on a document once it has been parsed.
+type Checker struct {
+	ast.BaseVisitor
+	reporter *diagnostic.Reporter
+}
+
+func NewChecker(reporter *diagnostic.Reporter) *Checker {
+	return &Checker{reporter: reporter}
+}
+
+// Check walks the document and reports any problems it finds.
+func (c *Checker) Check(doc *ast.Document) {
+	ast.Walk(c, doc)
+}
+
+func (c *Checker) VisitProperty(p *ast.Property) {
+	if p.Name == "style" {
+		c.checkStyle(p.Value)
+	}
+}
+
+// checkStyle validates a style value. A style is a class string, a template
+// string, a list of classes, or an object mapping classes to conditions.
+func (c *Checker) checkStyle(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.ArrayLiteral:
+		for _, el := range e.Elements {
+			if !isClassExpression(el) {
+				c.report("INVALID_STYLE", "style list entries must be class strings, found "+el.String(), el.Pos(), diagnostic.SeverityError)
+			}
+		}
+	case *ast.ObjectLiteral:
+		for _, p := range e.Properties {
+			if strings.TrimSpace(p.Key) == "" {
+				c.report("INVALID_STYLE", "style map keys must be class names", p.Position, diagnostic.SeverityError)
+			}
+			switch p.Value.(type) {
+			case *ast.ArrowFunction:
+				c.report("INVALID_STYLE", "the condition for '"+p.Key+"' is a function; call it or use a value", p.Value.Pos(), diagnostic.SeverityError)
+			case *ast.ArrayLiteral, *ast.ObjectLiteral, *ast.StringLiteral, *ast.TemplateLiteral:
+				c.report("CONSTANT_STYLE_CONDITION", "the condition for '"+p.Key+"' is always true", p.Value.Pos(), diagnostic.SeverityWarning)
+			}
+		}
+	default:
+		if !isClassExpression(expr) {
+			c.report("INVALID_STYLE", "style must be a class string, a list of classes or a map of classes to conditions, found "+expr.String(), expr.Pos(), diagnostic.SeverityError)
+		}
+	}
+}
+
+// isClassExpression reports whether expr can produce a class string.
+// Literals of any other type never can; anything computed might.
+func isClassExpression(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral, *ast.BooleanLiteral, *ast.NullLiteral,
+		*ast.ArrayLiteral, *ast.ObjectLiteral, *ast.ArrowFunction:
+		return false
+	case *ast.ConditionalExpression:
+		return isClassOrEmpty(e.Consequent) && isClassOrEmpty(e.Alternate)
+	}
+	return true
+}
+
+// isClassOrEmpty allows null as one branch of a conditional class.
+func isClassOrEmpty(expr ast.Expression) bool {
+	if _, ok := expr.(*ast.NullLiteral); ok {
+		return true
+	}
+	return isClassExpression(expr)
+}
+
+func (c *Checker) report(code diagnostic.DiagnosticCode, msg string, pos ast.Position, severity diagnostic.Severity) {
+	c.reporter.Add(diagnostic.NewDiagnostic(code, msg, diagnostic.Position{
+		Line:   pos.Line,
+		Column: pos.Column,
+		File:   pos.File,
+	}, severity, "checker"))
+}
@@ -1,6 +1,10 @@
 package compiler
 
 import (
+	"strconv"
+	"strings"
+
+	"github.com/antlr4-go/antlr/v4"
 	"github.com/yasufadhili/jawt/internal/ast"
 	parser "github.com/yasufadhili/jawt/internal/compiler/parser/generated"
 	"github.com/yasufadhili/jawt/internal/diagnostic"
@@ -10,6 +14,10 @@ type AstBuilder struct {
 	*parser.BaseJmlVisitor
 	reporter *diagnostic.Reporter
 	file     string
+
+	// fixedPos is set when building an expression embedded in a template
+	// string; every node then takes the position of the template literal.
+	fixedPos *ast.Position
 }
 
 func NewAstBuilder(file string, reporter *diagnostic.Reporter) *AstBuilder {
@@ -20,7 +28,424 @@ func NewAstBuilder(file string, reporter *diagnostic.Reporter) *AstBuilder {
 	}
 }
 
+// Visit dispatches to the Visit method matching the concrete parse tree node.
+func (b *AstBuilder) Visit(tree antlr.ParseTree) interface{} {
+	if tree == nil {
+		return nil
+	}
+	return tree.Accept(b)
+}
+
 func (b *AstBuilder) VisitDocument(ctx *parser.DocumentContext) interface{} {
+	doc := &ast.Document{
+		Position:   b.pos(ctx.GetStart()),
+		SourceFile: b.file,
+	}
+
+	if decl := ctx.DoctypeDeclaration(); decl != nil {
+		if decl.COMPONENT() != nil {
+			doc.DocType = ast.DocTypeComponent
+		} else {
+			doc.DocType = ast.DocTypePage
+		}
+		if id := decl.IDENTIFIER(); id != nil {
+			doc.Name = id.GetText()
+		}
+	}
+
+	for _, imp := range ctx.AllImportStatement() {
+		if node, ok := b.Visit(imp).(*ast.Import); ok {
+			doc.Imports = append(doc.Imports, node)
+		}
+	}
+
+	if el := ctx.Element(); el != nil {
+		doc.Root, _ = b.Visit(el).(*ast.Element)
+	}
+
+	return doc
+}
+
+func (b *AstBuilder) VisitImportStatement(ctx *parser.ImportStatementContext) interface{} {
+	imp := &ast.Import{Position: b.pos(ctx.GetStart())}
 
-	return &ast.Document{}
+	if id := ctx.IDENTIFIER(); id != nil {
+		imp.Name = id.GetText()
+	}
+
+	switch {
+	case ctx.COMPONENT() != nil:
+		imp.Kind = ast.ImportComponent
+		imp.Lazy = ctx.LAZY() != nil
+	case ctx.SCRIPT() != nil:
+		imp.Kind = ast.ImportScript
+	default:
+		imp.Kind = ast.ImportBuiltin
+	}
+
+	if str := ctx.STRING(); str != nil {
+		imp.Path = b.unquote(str.GetSymbol())
+	}
+
+	return imp
+}
+
+func (b *AstBuilder) VisitElement(ctx *parser.ElementContext) interface{} {
+	el := &ast.Element{
+		Position: b.pos(ctx.GetStart()),
+		Name:     ctx.IDENTIFIER().GetText(),
+	}
+
+	for _, member := range ctx.Block().AllElementMember() {
+		switch node := b.visitElementMember(member).(type) {
+		case *ast.Property:
+			if el.Property(node.Name) != nil {
+				b.report("DUPLICATE_PROPERTY", "property '"+node.Name+"' is already set on "+el.Name, node.Position, diagnostic.SeverityError)
+				continue
+			}
+			el.Properties = append(el.Properties, node)
+		case ast.Node:
+			el.Children = append(el.Children, node)
+		}
+	}
+
+	return el
+}
+
+func (b *AstBuilder) VisitForLoop(ctx *parser.ForLoopContext) interface{} {
+	return &ast.ForLoop{
+		Position: b.pos(ctx.GetStart()),
+		Variable: ctx.IDENTIFIER().GetText(),
+		Iterable: b.expression(ctx.Expression()),
+		Body:     b.blockChildren(ctx.Block(), "for"),
+	}
+}
+
+func (b *AstBuilder) VisitIfBlock(ctx *parser.IfBlockContext) interface{} {
+	block := &ast.IfBlock{
+		Position:  b.pos(ctx.GetStart()),
+		Condition: b.expression(ctx.Expression()),
+		Then:      b.blockChildren(ctx.Block(), "if"),
+	}
+
+	if elseCtx := ctx.ElseClause(); elseCtx != nil {
+		if nested := elseCtx.IfBlock(); nested != nil {
+			if node, ok := b.Visit(nested).(*ast.IfBlock); ok {
+				block.Else = []ast.Node{node}
+			}
+		} else {
+			block.Else = b.blockChildren(elseCtx.Block(), "else")
+		}
+	}
+
+	return block
+}
+
+func (b *AstBuilder) VisitProperty(ctx *parser.PropertyContext) interface{} {
+	return &ast.Property{
+		Position: b.pos(ctx.GetStart()),
+		Name:     ctx.IDENTIFIER().GetText(),
+		Value:    b.expression(ctx.Expression()),
+	}
+}
+
+// Expressions
+
+func (b *AstBuilder) VisitMemberExpression(ctx *parser.MemberExpressionContext) interface{} {
+	return &ast.MemberExpression{
+		Position: b.pos(ctx.GetStart()),
+		Object:   b.expression(ctx.Expression()),
+		Property: ctx.IDENTIFIER().GetText(),
+	}
+}
+
+func (b *AstBuilder) VisitIndexExpression(ctx *parser.IndexExpressionContext) interface{} {
+	return &ast.IndexExpression{
+		Position: b.pos(ctx.GetStart()),
+		Object:   b.expression(ctx.Expression(0)),
+		Index:    b.expression(ctx.Expression(1)),
+	}
+}
+
+func (b *AstBuilder) VisitCallExpression(ctx *parser.CallExpressionContext) interface{} {
+	call := &ast.CallExpression{
+		Position: b.pos(ctx.GetStart()),
+		Callee:   b.expression(ctx.Expression()),
+	}
+	if args := ctx.Arguments(); args != nil {
+		for _, arg := range args.AllExpression() {
+			call.Arguments = append(call.Arguments, b.expression(arg))
+		}
+	}
+	return call
+}
+
+func (b *AstBuilder) VisitUnaryExpression(ctx *parser.UnaryExpressionContext) interface{} {
+	return &ast.UnaryExpression{
+		Position: b.pos(ctx.GetStart()),
+		Operator: ctx.GetOp().GetText(),
+		Operand:  b.expression(ctx.Expression()),
+	}
+}
+
+func (b *AstBuilder) VisitBinaryExpression(ctx *parser.BinaryExpressionContext) interface{} {
+	return &ast.BinaryExpression{
+		Position: b.pos(ctx.GetStart()),
+		Operator: ctx.GetOp().GetText(),
+		Left:     b.expression(ctx.Expression(0)),
+		Right:    b.expression(ctx.Expression(1)),
+	}
+}
+
+func (b *AstBuilder) VisitConditionalExpression(ctx *parser.ConditionalExpressionContext) interface{} {
+	return &ast.ConditionalExpression{
+		Position:   b.pos(ctx.GetStart()),
+		Test:       b.expression(ctx.Expression(0)),
+		Consequent: b.expression(ctx.Expression(1)),
+		Alternate:  b.expression(ctx.Expression(2)),
+	}
+}
+
+func (b *AstBuilder) VisitArrowFunctionExpression(ctx *parser.ArrowFunctionExpressionContext) interface{} {
+	fn := ctx.ArrowFunction()
+	arrow := &ast.ArrowFunction{
+		Position: b.pos(fn.GetStart()),
+		Body:     b.expression(fn.Expression()),
+	}
+	if id := fn.IDENTIFIER(); id != nil {
+		arrow.Params = []string{id.GetText()}
+	} else if params := fn.ParameterList(); params != nil {
+		for _, p := range params.AllIDENTIFIER() {
+			arrow.Params = append(arrow.Params, p.GetText())
+		}
+	}
+	return arrow
+}
+
+func (b *AstBuilder) VisitParenthesizedExpression(ctx *parser.ParenthesizedExpressionContext) interface{} {
+	return b.expression(ctx.Expression())
+}
+
+func (b *AstBuilder) VisitArrayExpression(ctx *parser.ArrayExpressionContext) interface{} {
+	lit := ctx.ArrayLiteral()
+	arr := &ast.ArrayLiteral{Position: b.pos(lit.GetStart())}
+	for _, e := range lit.AllExpression() {
+		arr.Elements = append(arr.Elements, b.expression(e))
+	}
+	return arr
+}
+
+func (b *AstBuilder) VisitObjectExpression(ctx *parser.ObjectExpressionContext) interface{} {
+	lit := ctx.ObjectLiteral()
+	obj := &ast.ObjectLiteral{Position: b.pos(lit.GetStart())}
+	for _, p := range lit.AllObjectProperty() {
+		prop := &ast.ObjectProperty{
+			Position: b.pos(p.GetStart()),
+			Value:    b.expression(p.Expression()),
+		}
+		if id := p.IDENTIFIER(); id != nil {
+			prop.Key = id.GetText()
+		} else {
+			prop.Key = b.unquote(p.STRING().GetSymbol())
+		}
+		obj.Properties = append(obj.Properties, prop)
+	}
+	return obj
+}
+
+func (b *AstBuilder) VisitLiteralExpression(ctx *parser.LiteralExpressionContext) interface{} {
+	lit := ctx.Literal()
+	pos := b.pos(lit.GetStart())
+
+	switch {
+	case lit.STRING() != nil:
+		return &ast.StringLiteral{Position: pos, Value: b.unquote(lit.STRING().GetSymbol())}
+	case lit.TEMPLATE_STRING() != nil:
+		return b.templateLiteral(lit.TEMPLATE_STRING().GetSymbol())
+	case lit.NUMBER() != nil:
+		raw := lit.NUMBER().GetText()
+		value, _ := strconv.ParseFloat(raw, 64)
+		return &ast.NumberLiteral{Position: pos, Value: value, Raw: raw}
+	case lit.TRUE() != nil:
+		return &ast.BooleanLiteral{Position: pos, Value: true}
+	case lit.FALSE() != nil:
+		return &ast.BooleanLiteral{Position: pos, Value: false}
+	default:
+		return &ast.NullLiteral{Position: pos}
+	}
+}
+
+func (b *AstBuilder) VisitIdentifierExpression(ctx *parser.IdentifierExpressionContext) interface{} {
+	return &ast.Identifier{
+		Position: b.pos(ctx.GetStart()),
+		Name:     ctx.IDENTIFIER().GetText(),
+	}
+}
+
+// Helpers
+
+func (b *AstBuilder) visitElementMember(ctx parser.IElementMemberContext) interface{} {
+	switch {
+	case ctx.Property() != nil:
+		return b.Visit(ctx.Property())
+	case ctx.Element() != nil:
+		return b.Visit(ctx.Element())
+	case ctx.ForLoop() != nil:
+		return b.Visit(ctx.ForLoop())
+	case ctx.IfBlock() != nil:
+		return b.Visit(ctx.IfBlock())
+	}
+	return nil
+}
+
+// blockChildren builds the body of a for, if or else block. Properties are
+// only meaningful on elements, so they are reported and dropped here.
+func (b *AstBuilder) blockChildren(ctx parser.IBlockContext, owner string) []ast.Node {
+	var children []ast.Node
+	for _, member := range ctx.AllElementMember() {
+		switch node := b.visitElementMember(member).(type) {
+		case *ast.Property:
+			b.report("PROPERTY_OUTSIDE_ELEMENT",
+				"property '"+node.Name+"' cannot be set directly inside an '"+owner+"' block",
+				node.Position, diagnostic.SeverityError)
+		case ast.Node:
+			children = append(children, node)
+		}
+	}
+	return children
+}
+
+func (b *AstBuilder) expression(ctx parser.IExpressionContext) ast.Expression {
+	if ctx != nil {
+		if expr, ok := b.Visit(ctx).(ast.Expression); ok {
+			return expr
+		}
+	}
+	// The parser has already reported a syntax error for the missing expression.
+	return &ast.NullLiteral{}
+}
+
+// templateLiteral splits a backtick string into its static text and the
+// expressions inside ${...}, parsing each expression on its own.
+func (b *AstBuilder) templateLiteral(tok antlr.Token) *ast.TemplateLiteral {
+	pos := b.pos(tok)
+	text := tok.GetText()
+	text = text[1 : len(text)-1]
+
+	tmpl := &ast.TemplateLiteral{Position: pos}
+	var quasi strings.Builder
+
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text):
+			quasi.WriteString(unescape(text[i : i+2]))
+			i++
+		case text[i] == '$' && i+1 < len(text) && text[i+1] == '{':
+			end := matchingBrace(text, i+1)
+			if end < 0 {
+				b.report("UNTERMINATED_TEMPLATE", "unterminated ${ in template string", pos, diagnostic.SeverityError)
+				quasi.WriteString(text[i:])
+				i = len(text)
+				continue
+			}
+			tmpl.Quasis = append(tmpl.Quasis, quasi.String())
+			quasi.Reset()
+			tmpl.Expressions = append(tmpl.Expressions, b.parseEmbedded(text[i+2:end], pos))
+			i = end
+		default:
+			quasi.WriteByte(text[i])
+		}
+	}
+	tmpl.Quasis = append(tmpl.Quasis, quasi.String())
+
+	return tmpl
+}
+
+// parseEmbedded parses an expression found inside a template string.
+func (b *AstBuilder) parseEmbedded(src string, pos ast.Position) ast.Expression {
+	lexer := parser.NewJmlLexer(antlr.NewInputStream(src))
+	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
+	p := parser.NewJmlParser(stream)
+
+	p.RemoveErrorListeners()
+	p.AddErrorListener(diagnostic.NewAntlrErrorListener(b.reporter, b.file))
+
+	nested := &AstBuilder{
+		BaseJmlVisitor: b.BaseJmlVisitor,
+		reporter:       b.reporter,
+		file:           b.file,
+		fixedPos:       &pos,
+	}
+	return nested.expression(p.Expression())
+}
+
+func (b *AstBuilder) unquote(tok antlr.Token) string {
+	text := tok.GetText()
+	if len(text) < 2 {
+		return text
+	}
+	inner := text[1 : len(text)-1]
+
+	var sb strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			sb.WriteString(unescape(inner[i : i+2]))
+			i++
+			continue
+		}
+		sb.WriteByte(inner[i])
+	}
+	return sb.String()
+}
+
+func (b *AstBuilder) pos(tok antlr.Token) ast.Position {
+	if b.fixedPos != nil {
+		return *b.fixedPos
+	}
+	if tok == nil {
+		return ast.Position{File: b.file}
+	}
+	return ast.Position{
+		Line:   tok.GetLine(),
+		Column: tok.GetColumn(),
+		File:   b.file,
+	}
+}
+
+func (b *AstBuilder) report(code diagnostic.DiagnosticCode, msg string, pos ast.Position, severity diagnostic.Severity) {
+	b.reporter.Add(diagnostic.NewDiagnostic(code, msg, diagnostic.Position{
+		Line:   pos.Line,
+		Column: pos.Column,
+		File:   pos.File,
+	}, severity, "compiler"))
+}
+
+func unescape(seq string) string {
+	switch seq[1] {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	default:
+		return seq[1:]
+	}
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open, or -1.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
 }
@@ -0,0 +1,77 @@
+package compiler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+)
+
+// classCollector gathers the class names used in style properties.
+type classCollector struct {
+	ast.BaseVisitor
+	classes map[string]bool
+}
+
+// ExtractClasses returns the sorted set of classes a document uses in its
+// style properties. Every branch of a conditional, every entry of a class
+// list and every key of a class map is included, as are the literal parts
+// of concatenated and template strings.
+func ExtractClasses(doc *ast.Document) []string {
+	c := &classCollector{classes: make(map[string]bool)}
+	ast.Walk(c, doc)
+
+	classes := make([]string, 0, len(c.classes))
+	for class := range c.classes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+func (c *classCollector) VisitProperty(p *ast.Property) {
+	if p.Name == "style" {
+		c.collect(p.Value)
+	}
+}
+
+func (c *classCollector) collect(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		c.add(e.Value)
+	case *ast.TemplateLiteral:
+		for _, q := range e.Quasis {
+			c.add(q)
+		}
+	case *ast.BinaryExpression:
+		switch e.Operator {
+		case "+", "||":
+			c.collect(e.Left)
+			c.collect(e.Right)
+		case "&&":
+			c.collect(e.Right)
+		}
+	case *ast.ConditionalExpression:
+		c.collect(e.Consequent)
+		c.collect(e.Alternate)
+	case *ast.ArrayLiteral:
+		for _, el := range e.Elements {
+			c.collect(el)
+		}
+	case *ast.ObjectLiteral:
+		// In a class map the keys are the classes.
+		for _, p := range e.Properties {
+			c.add(p.Key)
+		}
+	}
+}
+
+func (c *classCollector) add(s string) {
+	for _, class := range strings.Fields(s) {
+		// Fragments such as "bg-" in "bg-" + color are not classes by themselves.
+		if strings.HasSuffix(class, "-") {
+			continue
+		}
+		c.classes[class] = true
+	}
+}
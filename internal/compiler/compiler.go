@@ -6,6 +6,7 @@ import (
 
 	"github.com/antlr4-go/antlr/v4"
 	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/checker"
 	"github.com/yasufadhili/jawt/internal/core"
 	"github.com/yasufadhili/jawt/internal/diagnostic"
 )
@@ -29,18 +30,23 @@ func (c *Compiler) Compile(file string, reporter *diagnostic.Reporter) (*ast.Doc
 
 	lexer := parser.NewJmlLexer(input)
 	stream := antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel)
-	parser := parser.NewJmlParser(stream)
+	p := parser.NewJmlParser(stream)
 
 	// Remove default error listeners and add our custom one
-	parser.RemoveErrorListeners()
-	// parser.AddErrorListener(diagnostic.NewAntlrErrorListener(file, reporter))
+	lexer.RemoveErrorListeners()
+	lexer.AddErrorListener(diagnostic.NewAntlrErrorListener(reporter, file))
+	p.RemoveErrorListeners()
+	p.AddErrorListener(diagnostic.NewAntlrErrorListener(reporter, file))
 
 	// Parse the input
-	tree := parser.Document()
+	tree := p.Document()
 
 	// Build the AST
 	builder := NewAstBuilder(file, reporter)
 	astDoc := builder.Visit(tree).(*ast.Document)
 
+	// Check the semantics of the document
+	checker.NewChecker(reporter).Check(astDoc)
+
 	return astDoc, nil
 }
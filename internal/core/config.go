@@ -3,6 +3,7 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 )
@@ -23,6 +24,10 @@ type JawtConfig struct {
 	EnableMinification bool `json:"enable_minification"`
 	EnableSourceMaps   bool `json:"enable_source_maps"`
 	EnableTreeShaking  bool `json:"enable_tree_shaking"`
+
+	// CompileConcurrency limits how many documents are compiled at once.
+	// Zero uses one worker per CPU.
+	CompileConcurrency int `json:"compile_concurrency"`
 }
 
 // ProjectConfig represents the new project-specific configuration structure
@@ -62,17 +67,96 @@ type ProjectConfig struct {
 		PreBuild  []string `json:"preBuild"`
 		PostBuild []string `json:"postBuild"`
 	} `json:"scripts"`
+	Images struct {
+		Widths []int  `json:"widths"`
+		Sizes  string `json:"sizes"`
+	} `json:"images"`
+	Budgets []Budget `json:"budgets"`
+	CSP     struct {
+		Enabled bool `json:"enabled"`
+		// Directives adds sources to the generated policy, e.g.
+		// {"connect-src": ["https://api.example.com"]}.
+		Directives map[string][]string `json:"directives"`
+	} `json:"csp"`
+	PWA struct {
+		Enabled         bool      `json:"enabled"`
+		ShortName       string    `json:"shortName"`
+		ThemeColor      string    `json:"themeColor"`
+		BackgroundColor string    `json:"backgroundColor"`
+		Display         string    `json:"display"`
+		StartURL        string    `json:"startUrl"`
+		Icons           []PWAIcon `json:"icons"`
+		// RuntimeCaching is how the service worker handles requests that
+		// aren't precached: "network-first", "cache-first" or
+		// "stale-while-revalidate".
+		RuntimeCaching string `json:"runtimeCaching"`
+	} `json:"pwa"`
+	Sitemap struct {
+		// BaseURL is the public URL of the site, e.g. https://example.com.
+		// sitemap.xml and robots.txt are only written when it is set.
+		BaseURL string `json:"baseUrl"`
+	} `json:"sitemap"`
+
+	// Lang is the language of the pages, written to <html lang>. A page
+	// can set its own with the lang property.
+	Lang string `json:"lang"`
+
+	// ScriptTimeout is how many seconds each preBuild and postBuild script
+	// may run. Zero means no limit.
+	ScriptTimeout int `json:"scriptTimeout"`
+
+	// SourceMaps keeps source maps in minified production builds.
+	SourceMaps bool `json:"sourceMaps"`
+
+	// Features are flags that client code reads from the env module.
+	Features map[string]bool `json:"features"`
+
+	// Type is "app", or "library" for a project that is built into a
+	// package other projects use.
+	Type string `json:"type"`
+
+	// Exports are the components and scripts a library makes available,
+	// relative to the project root, e.g. "components/button.jml".
+	Exports []string `json:"exports"`
+}
+
+// PWAIcon is an icon of the web app manifest. Src is a file in the assets
+// directory; Sizes and Type are worked out from the file if left empty.
+type PWAIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose"`
+}
+
+// Budget limits what a route may load, in kilobytes after gzip. A zero
+// limit is not checked. The route "*" applies to every route that has no
+// budget of its own.
+type Budget struct {
+	Route  string `json:"route"`
+	JS     int    `json:"js"`
+	CSS    int    `json:"css"`
+	Assets int    `json:"assets"`
+	Total  int    `json:"total"`
 }
 
 // BuildOptions represents build-time options and detected features
 type BuildOptions struct {
 	UsesTailwindCSS bool
+	Minify          bool
+	SourceMaps      bool
+	Library         bool   // compile declarations for a library package
+	Mode            string // e.g. "development", "staging" or "production"
 }
 
 // NewBuildOptions creates a new BuildOptions instance
 func NewBuildOptions() *BuildOptions {
 	return &BuildOptions{
 		UsesTailwindCSS: false,
+		Minify:          false,
+		SourceMaps:      true,
+		Library:         false,
+		Mode:            "development",
 	}
 }
 
@@ -157,6 +241,37 @@ func DefaultProjectConfig() *ProjectConfig {
 			PreBuild:  []string{},
 			PostBuild: []string{},
 		},
+		ScriptTimeout: 300,
+		Type:          "app",
+		Images: struct {
+			Widths []int  `json:"widths"`
+			Sizes  string `json:"sizes"`
+		}{
+			Widths: []int{320, 640, 960, 1280, 1920},
+			Sizes:  "100vw",
+		},
+		CSP: struct {
+			Enabled    bool                `json:"enabled"`
+			Directives map[string][]string `json:"directives"`
+		}{
+			Enabled: false,
+		},
+		Lang: "en",
+		PWA: struct {
+			Enabled         bool      `json:"enabled"`
+			ShortName       string    `json:"shortName"`
+			ThemeColor      string    `json:"themeColor"`
+			BackgroundColor string    `json:"backgroundColor"`
+			Display         string    `json:"display"`
+			StartURL        string    `json:"startUrl"`
+			Icons           []PWAIcon `json:"icons"`
+			RuntimeCaching  string    `json:"runtimeCaching"`
+		}{
+			Enabled:        false,
+			Display:        "standalone",
+			StartURL:       "/",
+			RuntimeCaching: "network-first",
+		},
 	}
 }
 
@@ -187,24 +302,8 @@ func LoadJawtConfig(configPath string) (*JawtConfig, error) {
 
 // LoadProjectConfig loads project configuration from the specified path
 func LoadProjectConfig(projectDir string) (*ProjectConfig, error) {
-	configPath := filepath.Join(projectDir, "jawt.project.json")
-
-	// Check if a config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return DefaultProjectConfig(), nil
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read project config: %w", err)
-	}
-
-	config := DefaultProjectConfig()
-	if err := json.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse project config: %w", err)
-	}
-
-	return config, nil
+	config, _, err := LoadProjectConfigForMode(projectDir, "")
+	return config, err
 }
 
 // Save saves the jawt configuration to the specified path
@@ -243,6 +342,10 @@ func (jc *JawtConfig) Validate() error {
 		return fmt.Errorf("cache directory cannot be empty")
 	}
 
+	if jc.CompileConcurrency < 0 {
+		return fmt.Errorf("invalid compile concurrency: %d", jc.CompileConcurrency)
+	}
+
 	return nil
 }
 
@@ -284,6 +387,62 @@ func (pc *ProjectConfig) Validate() error {
 		return fmt.Errorf("invalid dev server port: %d", pc.Dev.Port)
 	}
 
+	if pc.ScriptTimeout < 0 {
+		return fmt.Errorf("invalid script timeout: %d", pc.ScriptTimeout)
+	}
+
+	for _, width := range pc.Images.Widths {
+		if width <= 0 {
+			return fmt.Errorf("invalid image width: %d", width)
+		}
+	}
+
+	for _, budget := range pc.Budgets {
+		if budget.Route == "" {
+			return fmt.Errorf("budget route cannot be empty")
+		}
+		if budget.JS < 0 || budget.CSS < 0 || budget.Assets < 0 || budget.Total < 0 {
+			return fmt.Errorf("invalid budget for route %s: limits cannot be negative", budget.Route)
+		}
+	}
+
+	switch pc.PWA.RuntimeCaching {
+	case "network-first", "cache-first", "stale-while-revalidate":
+	default:
+		return fmt.Errorf("invalid PWA runtime caching strategy: %s", pc.PWA.RuntimeCaching)
+	}
+
+	for _, icon := range pc.PWA.Icons {
+		if icon.Src == "" {
+			return fmt.Errorf("PWA icon src cannot be empty")
+		}
+	}
+
+	switch pc.Type {
+	case "app", "library":
+	default:
+		return fmt.Errorf("invalid project type: %s", pc.Type)
+	}
+
+	for _, export := range pc.Exports {
+		switch filepath.Ext(export) {
+		case ".jml", ".ts", ".tsx":
+		default:
+			return fmt.Errorf("invalid export %s: only .jml components and .ts scripts can be exported", export)
+		}
+	}
+
+	if pc.Type == "library" && len(pc.Exports) == 0 {
+		return fmt.Errorf("a library must export at least one component or script")
+	}
+
+	if pc.Sitemap.BaseURL != "" {
+		u, err := url.Parse(pc.Sitemap.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid sitemap base URL: %s", pc.Sitemap.BaseURL)
+		}
+	}
+
 	return nil
 }
 
@@ -332,6 +491,11 @@ func (pc *ProjectConfig) IsMinificationEnabled() bool {
 	return pc.Build.Minify
 }
 
+// IsLibrary returns whether the project is a library
+func (pc *ProjectConfig) IsLibrary() bool {
+	return pc.Type == "library"
+}
+
 // IsShadowDOMEnabled returns whether Shadow DOM is enabled
 func (pc *ProjectConfig) IsShadowDOMEnabled() bool {
 	return pc.Build.ShadowDOM
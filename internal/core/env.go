@@ -0,0 +1,119 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PublicEnvPrefix marks the environment variables that may be inlined into
+// client code. Everything else stays on the machine running the build.
+const PublicEnvPrefix = "JAWT_PUBLIC_"
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadEnv returns the environment of a build in the given mode: the
+// variables in .env, then .env.<mode>, then the process environment, each
+// overriding the one before. Missing files are skipped.
+func LoadEnv(projectRoot, mode string) (map[string]string, error) {
+	env := make(map[string]string)
+
+	files := []string{".env"}
+	if mode != "" {
+		files = append(files, ".env."+mode)
+	}
+	for _, name := range files {
+		path := filepath.Join(projectRoot, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		vars, err := ParseEnv(string(data), path)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			env[k] = v
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			env[k] = v
+		}
+	}
+
+	return env, nil
+}
+
+// ParseEnv parses the contents of a .env file: KEY=value lines, optionally
+// starting with export, with # comments. Values may be quoted; double
+// quoted values understand \n, \t, \" and \\ escapes.
+func ParseEnv(data, file string) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	for i, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envNamePattern.MatchString(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", file, i+1)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(value) >= 2 && value[0] == '"' && strings.HasSuffix(value, `"`):
+			value = unescapeEnv(value[1 : len(value)-1])
+		case len(value) >= 2 && value[0] == '\'' && strings.HasSuffix(value, "'"):
+			value = value[1 : len(value)-1]
+		case value != "" && (value[0] == '"' || value[0] == '\''):
+			return nil, fmt.Errorf("%s:%d: unterminated quoted value for %s", file, i+1, key)
+		default:
+			// Unquoted values end at a comment
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+
+		vars[key] = value
+	}
+
+	return vars, nil
+}
+
+func unescapeEnv(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// IsPublicEnv reports whether a variable may be inlined into client code.
+func IsPublicEnv(name string) bool {
+	return strings.HasPrefix(name, PublicEnvPrefix)
+}
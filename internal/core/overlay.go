@@ -0,0 +1,160 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ConfigSources records which file set each configuration value, by dotted
+// key such as "build.minify". Keys no file sets have their default value.
+type ConfigSources map[string]string
+
+// ConfigValue is a value of the effective configuration.
+type ConfigValue struct {
+	Key    string
+	Value  string // as JSON
+	Source string // the file that set it, or "default"
+}
+
+var modePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ProjectConfigOverlay returns the name of the overlay file of a mode.
+func ProjectConfigOverlay(mode string) string {
+	return "jawt.project." + mode + ".json"
+}
+
+// LoadProjectConfigForMode loads the project configuration and merges the
+// overlay for mode, jawt.project.<mode>.json, on top of it. Objects are
+// merged key by key; any other value in the overlay, lists included,
+// replaces the base value. Either file may be missing.
+func LoadProjectConfigForMode(projectDir, mode string) (*ProjectConfig, ConfigSources, error) {
+	config := DefaultProjectConfig()
+	sources := make(ConfigSources)
+
+	files := []string{"jawt.project.json"}
+	if mode != "" {
+		if !modePattern.MatchString(mode) {
+			return nil, nil, fmt.Errorf("invalid mode %q: use lowercase letters, digits, - and _", mode)
+		}
+		files = append(files, ProjectConfigOverlay(mode))
+	}
+
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(projectDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		var raw map[string]interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		// A value from a later file, such as null for an object, replaces
+		// where anything under it came from
+		for _, key := range flattenJSON("", raw) {
+			for existing := range sources {
+				if strings.HasPrefix(existing, key+".") {
+					delete(sources, existing)
+				}
+			}
+			sources[key] = name
+		}
+	}
+
+	return config, sources, nil
+}
+
+// flattenJSON returns the dotted keys of the values in an object. Objects
+// are followed and every other value is a leaf. An empty object has no
+// leaves, since merging it changes nothing.
+func flattenJSON(prefix string, obj map[string]interface{}) []string {
+	var keys []string
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			keys = append(keys, flattenJSON(key, child)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Source returns the file that set a key, or "default". A key is also set
+// by a file that sets an object containing it.
+func (s ConfigSources) Source(key string) string {
+	for k := key; ; {
+		if src, ok := s[k]; ok {
+			return src
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			return "default"
+		}
+		k = k[:i]
+	}
+}
+
+// Values lists every value of the configuration in field order, with the
+// file it came from.
+func (pc *ProjectConfig) Values(sources ConfigSources) []ConfigValue {
+	var values []ConfigValue
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Struct:
+			t := v.Type()
+			for i := 0; i < t.NumField(); i++ {
+				name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+				if name == "" || name == "-" {
+					continue
+				}
+				walk(joinKey(prefix, name), v.Field(i))
+			}
+			return
+		case reflect.Map:
+			if v.Len() > 0 {
+				keys := make([]string, 0, v.Len())
+				for _, k := range v.MapKeys() {
+					keys = append(keys, k.String())
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					walk(joinKey(prefix, k), v.MapIndex(reflect.ValueOf(k)))
+				}
+				return
+			}
+		}
+
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			data = []byte(fmt.Sprint(v.Interface()))
+		}
+		values = append(values, ConfigValue{Key: prefix, Value: string(data), Source: sources.Source(prefix)})
+	}
+	walk("", reflect.ValueOf(pc).Elem())
+	return values
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProjectConfigForModeSources(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("jawt.project.json", `{"build": {"minify": false, "shadowDOM": true}, "features": {"beta": true}}`)
+	write(ProjectConfigOverlay("staging"), `{"build": {}, "features": {"search": true}, "sitemap": {"baseUrl": "https://staging.example.com"}}`)
+
+	config, sources, err := LoadProjectConfigForMode(dir, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Build.ShadowDOM || !config.Features["beta"] || !config.Features["search"] {
+		t.Errorf("Expected the overlay merged over the base, got %+v", config)
+	}
+
+	// An empty object in the overlay sets nothing
+	expected := map[string]string{
+		"build.minify":    "jawt.project.json",
+		"build.shadowDOM": "jawt.project.json",
+		"build.distDir":   "default",
+		"features.beta":   "jawt.project.json",
+		"features.search": "jawt.project.staging.json",
+		"sitemap.baseUrl": "jawt.project.staging.json",
+	}
+	for key, source := range expected {
+		if got := sources.Source(key); got != source {
+			t.Errorf("Expected %s to come from %s, got %s", key, source, got)
+		}
+	}
+}
@@ -124,6 +124,13 @@ func ResolveExecutablePath(cmd string) (string, error) {
 	return "", fmt.Errorf("executable '%s' not found in PATH or relative to JAWT executable", cmd)
 }
 
+// SetBuildDir moves the intermediate build artifacts, and the Tailwind
+// stylesheet kept with them, to dir.
+func (p *ProjectPaths) SetBuildDir(dir string) {
+	p.BuildDir = dir
+	p.TailwindCSSPath = filepath.Join(dir, "tailwind.css")
+}
+
 // EnsureDirectories creates all necessary directories
 func (p *ProjectPaths) EnsureDirectories() error {
 	// Create all necessary directories
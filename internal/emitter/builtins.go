@@ -0,0 +1,86 @@
+package emitter
+
+// builtin describes how a JML built-in component maps onto plain HTML.
+type builtin struct {
+	tag  string
+	void bool // void elements have no children and no closing tag
+}
+
+// builtins lists the components every JML document can use without importing them.
+var builtins = map[string]builtin{
+	"Container": {tag: "div"},
+	"Card":      {tag: "div"},
+	"Grid":      {tag: "div"},
+	"Text":      {tag: "span"},
+	"Heading":   {tag: "h2"},
+	"Paragraph": {tag: "p"},
+	"Header":    {tag: "header"},
+	"Main":      {tag: "main"},
+	"Footer":    {tag: "footer"},
+	"Section":   {tag: "section"},
+	"Article":   {tag: "article"},
+	"Nav":       {tag: "nav"},
+	"List":      {tag: "ul"},
+	"ListItem":  {tag: "li"},
+	"Button":    {tag: "button"},
+	"Link":      {tag: "a"},
+	"Form":      {tag: "form"},
+	"Label":     {tag: "label"},
+	"Image":     {tag: "img", void: true},
+	"Input":     {tag: "input", void: true},
+
+	// img is accepted as well, for markup written the HTML way
+	"img": {tag: "img", void: true},
+}
+
+// IsImageElement reports whether the element name renders an <img>.
+func IsImageElement(name string) bool {
+	return builtins[name].tag == "img"
+}
+
+// pageElement is the root element of every page document.
+const pageElement = "Page"
+
+// pageOnlyProperties are set on the Page element but describe the page
+// rather than anything that is rendered.
+var pageOnlyProperties = map[string]bool{
+	"title":        true,
+	"description":  true,
+	"staticParams": true,
+	"noindex":      true,
+	"lang":         true,
+}
+
+// isContentProperty reports whether a property becomes the text content
+// of a built-in element rather than an attribute.
+func isContentProperty(name string) bool {
+	return name == "content" || name == "text"
+}
+
+// isEventProperty reports whether a property is an event handler, e.g. onClick.
+func isEventProperty(name string) bool {
+	return len(name) > 2 && name[:2] == "on" && name[2] >= 'A' && name[2] <= 'Z'
+}
+
+// eventName converts an event handler property to the DOM event name: onClick -> click.
+func eventName(prop string) string {
+	return string(prop[2]+('a'-'A')) + prop[3:]
+}
+
+// placeholderProperty on an Image names an image, usually a tiny blurred
+// data URI, that is shown in its place until it has loaded.
+const placeholderProperty = "placeholder"
+
+// placeholderStyle is the inline style that shows a placeholder image behind
+// an img element.
+func placeholderStyle(url string) string {
+	return `background: center / cover no-repeat url("` + url + `")`
+}
+
+// attributeName converts a JML property to the HTML attribute it sets.
+func attributeName(prop string) string {
+	if prop == "style" {
+		return "class"
+	}
+	return prop
+}
@@ -1,10 +1,19 @@
 package emitter
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/yasufadhili/jawt/internal/ast"
 	"github.com/yasufadhili/jawt/internal/core"
 )
 
+// HydrateSupport must be loaded before lit so that components hydrate
+// prerendered declarative shadow roots instead of rendering them again.
+const HydrateSupport = "@lit-labs/ssr-client/lit-element-hydrate-support.js"
+
 type Emitter struct {
 	ctx *core.JawtContext
 }
@@ -15,9 +24,217 @@ func NewEmitter(ctx *core.JawtContext) *Emitter {
 
 // Emit takes an AST document and emits TypeScript code to the workspace.
 func (e *Emitter) Emit(doc *ast.Document) error {
+	outPath := e.ModulePath(doc.SourceFile)
+
 	e.ctx.Logger.Info("Emitting TypeScript for JML document",
-		core.StringField("name", ""),
-		core.StringField("path", ""))
+		core.StringField("name", doc.Name),
+		core.StringField("path", outPath))
+
+	code, err := e.Module(doc)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(outPath, []byte(code), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
 
 	return nil
 }
+
+// Module generates the TypeScript module defining the Lit element for doc.
+func (e *Emitter) Module(doc *ast.Document) (string, error) {
+	builder := newTemplateBuilder(e, doc)
+	tmpl, err := builder.buildDocument()
+	if err != nil {
+		return "", err
+	}
+
+	self := e.ModulePath(doc.SourceFile)
+	page := doc.DocType == ast.DocTypePage
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by jawt from %s. DO NOT EDIT.\n\n",
+		filepath.ToSlash(e.ctx.Paths.GetRelativePath(doc.SourceFile)))
+
+	if page && e.shadowDOM() {
+		fmt.Fprintf(&sb, "import '%s';\n", HydrateSupport)
+	}
+	sb.WriteString("import { LitElement, html, nothing } from 'lit';\n")
+	if builder.classMap {
+		sb.WriteString("import { classMap } from 'lit/directives/class-map.js';\n")
+	}
+
+	for _, imp := range doc.Imports {
+		switch imp.Kind {
+		case ast.ImportComponent:
+			if imp.ResolvedPath == "" {
+				return "", fmt.Errorf("%s:%d:%d: unresolved component import %q", imp.File, imp.Line, imp.Column, imp.Path)
+			}
+			switch {
+			case imp.Lazy && imp.ResolvedPath == doc.SourceFile:
+				// The element is defined by this module
+			case imp.Lazy:
+				// Loaded once this module has run, so an import cycle
+				// doesn't leave either element undefined
+				fmt.Fprintf(&sb, "void import('%s');\n", importPath(self, e.ModulePath(imp.ResolvedPath)))
+			default:
+				fmt.Fprintf(&sb, "import '%s';\n", importPath(self, e.ModulePath(imp.ResolvedPath)))
+			}
+		case ast.ImportScript:
+			if imp.ResolvedPath == "" {
+				return "", fmt.Errorf("%s:%d:%d: unresolved script import %q", imp.File, imp.Line, imp.Column, imp.Path)
+			}
+			fmt.Fprintf(&sb, "import * as %s from '%s';\n", imp.Name, importPath(self, e.ModulePath(imp.ResolvedPath)))
+		case ast.ImportBuiltin:
+			builtinPath := filepath.Join(e.ctx.Paths.InternalSrcDir, imp.Name+".ts")
+			fmt.Fprintf(&sb, "import * as %s from '%s';\n", imp.Name, importPath(self, builtinPath))
+		}
+	}
+
+	// Pages receive their route params, components their props.
+	input := "props"
+	if page {
+		input = "params"
+	}
+
+	className := e.ClassName(doc)
+	fmt.Fprintf(&sb, "\nexport class %s extends LitElement {\n", className)
+	fmt.Fprintf(&sb, "  static properties = {\n    %s: { attribute: false },\n  };\n\n", input)
+	fmt.Fprintf(&sb, "  declare %s: Record<string, any>;\n\n", input)
+	fmt.Fprintf(&sb, "  constructor() {\n    super();\n    this.%s = {};\n  }\n\n", input)
+	if !e.shadowDOM() {
+		// Render into the light DOM, discarding any prerendered markup.
+		sb.WriteString("  createRenderRoot() {\n    this.replaceChildren();\n    return this;\n  }\n\n")
+	}
+	sb.WriteString("  render() {\n")
+	fmt.Fprintf(&sb, "    const %s = this.%s;\n", input, input)
+	fmt.Fprintf(&sb, "    return %s;\n", tmpl.typescript())
+	sb.WriteString("  }\n}\n\n")
+	fmt.Fprintf(&sb, "customElements.define('%s', %s);\n", e.TagName(doc.SourceFile), className)
+
+	return sb.String(), nil
+}
+
+// ModulePath returns where the TypeScript module for a source file lives in
+// the workspace. JML documents are emitted under their project-relative path
+// and scripts keep the location they are copied to by the build system.
+func (e *Emitter) ModulePath(source string) string {
+	paths := e.ctx.Paths
+	if strings.HasSuffix(source, ".jml") {
+		rel := paths.GetRelativePath(source)
+		return filepath.Join(paths.UserSrcDir, strings.TrimSuffix(rel, ".jml")+".ts")
+	}
+	if rel, ok := within(paths.ScriptsDir, source); ok {
+		return filepath.Join(paths.UserSrcDir, rel)
+	}
+	return filepath.Join(paths.UserSrcDir, paths.GetRelativePath(source))
+}
+
+// ModuleURL returns the URL the compiled module for a source file is served from.
+func (e *Emitter) ModuleURL(source string) string {
+	rel, err := filepath.Rel(e.ctx.Paths.SrcDir, e.ModulePath(source))
+	if err != nil {
+		rel = filepath.Base(source)
+	}
+	return "/" + filepath.ToSlash(jsExtension(rel))
+}
+
+// TagName returns the custom element name for a JML document. Names are
+// derived from the file path so that they are unique across the project.
+func (e *Emitter) TagName(source string) string {
+	paths := e.ctx.Paths
+	if rel, ok := within(paths.AppDir, source); ok {
+		return "page-" + slug(rel)
+	}
+	if rel, ok := within(paths.ComponentsDir, source); ok {
+		return "jawt-" + slug(rel)
+	}
+	return "jawt-" + slug(paths.GetRelativePath(source))
+}
+
+// ClassName returns the name of the generated element class.
+func (e *Emitter) ClassName(doc *ast.Document) string {
+	name := pascalCase(doc.Name)
+	if name == "" {
+		name = "Jawt"
+	}
+	if doc.DocType == ast.DocTypePage {
+		name += "Page"
+	}
+	return name
+}
+
+func (e *Emitter) shadowDOM() bool {
+	return e.ctx.ProjectConfig.Build.ShadowDOM
+}
+
+// within returns path relative to dir if path is inside dir.
+func within(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// importPath returns the specifier used to import module to from module from.
+func importPath(from, to string) string {
+	rel, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		rel = to
+	}
+	rel = filepath.ToSlash(jsExtension(rel))
+	if !strings.HasPrefix(rel, ".") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// jsExtension replaces a TypeScript extension with the .js extension tsc emits.
+func jsExtension(path string) string {
+	switch filepath.Ext(path) {
+	case ".ts", ".tsx":
+		return strings.TrimSuffix(path, filepath.Ext(path)) + ".js"
+	}
+	return path
+}
+
+// slug turns a relative path into a lowercase name usable in a custom element tag.
+func slug(rel string) string {
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(rel) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
+
+func pascalCase(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case r == '-' || r == '_' || r == ' ' || r == '.':
+			upper = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9' && sb.Len() > 0):
+			if upper && r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			sb.WriteRune(r)
+			upper = false
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,365 @@
+package emitter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+)
+
+// errNotStatic is returned when an expression depends on something that is
+// only known in the browser, such as a function call or a script import.
+var errNotStatic = errors.New("expression cannot be evaluated at build time")
+
+// undefined is the JavaScript undefined value.
+type undefined struct{}
+
+// scope holds the variables visible to an expression: props, params and
+// loop variables.
+type scope struct {
+	vars   map[string]interface{}
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: make(map[string]interface{}), parent: parent}
+}
+
+func (s *scope) lookup(name string) (interface{}, bool) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if v, ok := cur.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// evaluate computes the value of an expression with JavaScript semantics.
+// Values are nil (null), undefined, bool, float64, string, []interface{}
+// and map[string]interface{}.
+func evaluate(expr ast.Expression, s *scope) (interface{}, error) {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return e.Value, nil
+	case *ast.NumberLiteral:
+		return e.Value, nil
+	case *ast.BooleanLiteral:
+		return e.Value, nil
+	case *ast.NullLiteral:
+		return nil, nil
+	case *ast.Identifier:
+		if v, ok := s.lookup(e.Name); ok {
+			return v, nil
+		}
+		if e.Name == "undefined" {
+			return undefined{}, nil
+		}
+		return nil, errNotStatic
+	case *ast.TemplateLiteral:
+		var sb strings.Builder
+		for i, q := range e.Quasis {
+			sb.WriteString(q)
+			if i < len(e.Expressions) {
+				v, err := evaluate(e.Expressions[i], s)
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(toString(v))
+			}
+		}
+		return sb.String(), nil
+	case *ast.ArrayLiteral:
+		out := make([]interface{}, 0, len(e.Elements))
+		for _, el := range e.Elements {
+			v, err := evaluate(el, s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case *ast.ObjectLiteral:
+		out := make(map[string]interface{}, len(e.Properties))
+		for _, p := range e.Properties {
+			v, err := evaluate(p.Value, s)
+			if err != nil {
+				return nil, err
+			}
+			out[p.Key] = v
+		}
+		return out, nil
+	case *ast.MemberExpression:
+		obj, err := evaluate(e.Object, s)
+		if err != nil {
+			return nil, err
+		}
+		return member(obj, e.Property)
+	case *ast.IndexExpression:
+		obj, err := evaluate(e.Object, s)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := evaluate(e.Index, s)
+		if err != nil {
+			return nil, err
+		}
+		if arr, ok := obj.([]interface{}); ok {
+			if n, ok := idx.(float64); ok {
+				if n >= 0 && n < float64(len(arr)) && n == math.Trunc(n) {
+					return arr[int(n)], nil
+				}
+				return undefined{}, nil
+			}
+		}
+		return member(obj, toString(idx))
+	case *ast.UnaryExpression:
+		v, err := evaluate(e.Operand, s)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case "!":
+			return !truthy(v), nil
+		case "-":
+			return -toNumber(v), nil
+		}
+	case *ast.BinaryExpression:
+		return evaluateBinary(e, s)
+	case *ast.ConditionalExpression:
+		test, err := evaluate(e.Test, s)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(test) {
+			return evaluate(e.Consequent, s)
+		}
+		return evaluate(e.Alternate, s)
+	}
+
+	// Calls, arrow functions and anything else run in the browser.
+	return nil, errNotStatic
+}
+
+func evaluateBinary(e *ast.BinaryExpression, s *scope) (interface{}, error) {
+	left, err := evaluate(e.Left, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit and return one of their operands.
+	switch e.Operator {
+	case "&&":
+		if !truthy(left) {
+			return left, nil
+		}
+		return evaluate(e.Right, s)
+	case "||":
+		if truthy(left) {
+			return left, nil
+		}
+		return evaluate(e.Right, s)
+	}
+
+	right, err := evaluate(e.Right, s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Operator {
+	case "+":
+		_, ls := left.(string)
+		_, rs := right.(string)
+		if ls || rs {
+			return toString(left) + toString(right), nil
+		}
+		return toNumber(left) + toNumber(right), nil
+	case "-":
+		return toNumber(left) - toNumber(right), nil
+	case "*":
+		return toNumber(left) * toNumber(right), nil
+	case "/":
+		return toNumber(left) / toNumber(right), nil
+	case "%":
+		return math.Mod(toNumber(left), toNumber(right)), nil
+	case "<", ">", "<=", ">=":
+		return compare(e.Operator, left, right), nil
+	case "===":
+		return strictEquals(left, right), nil
+	case "!==":
+		return !strictEquals(left, right), nil
+	case "==":
+		return looseEquals(left, right), nil
+	case "!=":
+		return !looseEquals(left, right), nil
+	}
+
+	return nil, fmt.Errorf("unsupported operator %s", e.Operator)
+}
+
+func member(obj interface{}, name string) (interface{}, error) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		if v, ok := o[name]; ok {
+			return v, nil
+		}
+		return undefined{}, nil
+	case []interface{}:
+		if name == "length" {
+			return float64(len(o)), nil
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < len(o) {
+			return o[n], nil
+		}
+		return nil, errNotStatic
+	case string:
+		if name == "length" {
+			return float64(len([]rune(o))), nil
+		}
+		return nil, errNotStatic
+	case nil, undefined:
+		return nil, fmt.Errorf("cannot read property %q of %s", name, toString(obj))
+	}
+	return nil, errNotStatic
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil, undefined:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func toNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float64:
+		return v
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return 0
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+	return math.NaN()
+}
+
+// toString converts a value the way JavaScript's String() does.
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case undefined:
+		return "undefined"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatNumber(v)
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, el := range v {
+			if el != nil && el != (undefined{}) {
+				parts[i] = toString(el)
+			}
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		return "[object Object]"
+	}
+	return fmt.Sprint(v)
+}
+
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	case n == math.Trunc(n) && math.Abs(n) < 1e21:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+func compare(op string, left, right interface{}) bool {
+	if ls, ok := left.(string); ok {
+		if rs, ok := right.(string); ok {
+			switch op {
+			case "<":
+				return ls < rs
+			case ">":
+				return ls > rs
+			case "<=":
+				return ls <= rs
+			default:
+				return ls >= rs
+			}
+		}
+	}
+
+	l, r := toNumber(left), toNumber(right)
+	switch op {
+	case "<":
+		return l < r
+	case ">":
+		return l > r
+	case "<=":
+		return l <= r
+	default:
+		return l >= r
+	}
+}
+
+func strictEquals(left, right interface{}) bool {
+	switch l := left.(type) {
+	case nil, undefined, bool, float64, string:
+		return left == right
+	case []interface{}:
+		r, ok := right.([]interface{})
+		return ok && len(l) > 0 && len(r) > 0 && &l[0] == &r[0]
+	}
+	// Distinct objects are never identical; evaluation always builds new ones.
+	return false
+}
+
+func looseEquals(left, right interface{}) bool {
+	isNullish := func(v interface{}) bool { return v == nil || v == (undefined{}) }
+	if isNullish(left) || isNullish(right) {
+		return isNullish(left) && isNullish(right)
+	}
+	switch left.(type) {
+	case float64, string, bool:
+		switch right.(type) {
+		case float64, string, bool:
+			if ls, ok := left.(string); ok {
+				if rs, ok := right.(string); ok {
+					return ls == rs
+				}
+			}
+			return toNumber(left) == toNumber(right)
+		}
+	}
+	return strictEquals(left, right)
+}
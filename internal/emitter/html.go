@@ -0,0 +1,122 @@
+package emitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTMLDocument is a prerendered page ready to be written as index.html.
+type HTMLDocument struct {
+	Lang        string
+	Title       string
+	Description string
+	NoIndex     bool // asks search engines not to index the page
+
+	// Tag is the custom element of the page and Body its prerendered content.
+	Tag  string
+	Body string
+
+	// Module is the URL of the compiled page module, or of the chunk that
+	// contains it. Preload lists the modules it loads, including itself.
+	Module  string
+	Preload []string
+	Params  map[string]string
+
+	// Router is the URL of the client router, started once the page module
+	// has loaded. Without it, every navigation loads a new page.
+	Router string
+
+	Stylesheets []string
+	Head        []string // extra markup for <head>, written before any module is loaded
+
+	// CriticalCSS is inlined in <head>, and DeferredStylesheets are loaded
+	// by the boot script once the page has been parsed.
+	CriticalCSS         string
+	DeferredStylesheets []string
+
+	// CSP is written as a Content-Security-Policy meta tag.
+	CSP string
+}
+
+// Render returns the complete HTML page.
+func (d *HTMLDocument) Render() string {
+	var sb strings.Builder
+
+	sb.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(&sb, "<html lang=\"%s\">\n", html.EscapeString(d.Lang))
+	sb.WriteString("<head>\n")
+	sb.WriteString("<meta charset=\"utf-8\">\n")
+	if d.CSP != "" {
+		fmt.Fprintf(&sb, "<meta http-equiv=\"Content-Security-Policy\" content=\"%s\">\n", html.EscapeString(d.CSP))
+	}
+	sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n", html.EscapeString(d.Title))
+	if d.Description != "" {
+		fmt.Fprintf(&sb, "<meta name=\"description\" content=\"%s\">\n", html.EscapeString(d.Description))
+	}
+	if d.NoIndex {
+		sb.WriteString("<meta name=\"robots\" content=\"noindex\">\n")
+	}
+	for _, href := range d.Stylesheets {
+		fmt.Fprintf(&sb, "<link rel=\"stylesheet\" href=\"%s\">\n", html.EscapeString(href))
+	}
+	if d.CriticalCSS != "" {
+		// A literal </style> would end the element early
+		fmt.Fprintf(&sb, "<style>%s</style>\n", strings.ReplaceAll(d.CriticalCSS, "</", "<\\/"))
+	}
+	for _, href := range d.DeferredStylesheets {
+		fmt.Fprintf(&sb, "<link rel=\"preload\" href=\"%s\" as=\"style\">\n", html.EscapeString(href))
+		fmt.Fprintf(&sb, "<noscript><link rel=\"stylesheet\" href=\"%s\"></noscript>\n", html.EscapeString(href))
+	}
+	// Head holds the import map, which has to come before anything fetches
+	// a module, preloads included
+	for _, h := range d.Head {
+		sb.WriteString(h)
+		sb.WriteString("\n")
+	}
+	for _, href := range d.Preload {
+		fmt.Fprintf(&sb, "<link rel=\"modulepreload\" href=\"%s\">\n", html.EscapeString(href))
+	}
+	sb.WriteString("</head>\n")
+
+	sb.WriteString("<body>\n")
+	fmt.Fprintf(&sb, "<%s>%s</%s>\n", d.Tag, d.Body, d.Tag)
+	sb.WriteString("<script type=\"module\">\n")
+	sb.WriteString(d.BootScript())
+	sb.WriteString("</script>\n")
+	sb.WriteString("</body>\n")
+	sb.WriteString("</html>\n")
+
+	return sb.String()
+}
+
+// BootScript returns the inline module that loads the page, any deferred
+// stylesheets and the router. The route
+// params are set before the module defines the element so that its first
+// render, and therefore hydration, sees the same values as the prerender.
+func (d *HTMLDocument) BootScript() string {
+	params := d.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+	// json.Marshal escapes <, > and & so the params cannot close the script.
+	data, _ := json.Marshal(params)
+	module, _ := json.Marshal(d.Module)
+
+	var sb strings.Builder
+	for _, href := range d.DeferredStylesheets {
+		h, _ := json.Marshal(href)
+		fmt.Fprintf(&sb, "document.head.append(Object.assign(document.createElement('link'), { rel: 'stylesheet', href: %s }));\n", h)
+	}
+	fmt.Fprintf(&sb, "document.querySelector(%q).params = %s;\n", d.Tag, data)
+	if d.Router == "" {
+		fmt.Fprintf(&sb, "import(%s);\n", module)
+		return sb.String()
+	}
+	router, _ := json.Marshal(d.Router)
+	tag, _ := json.Marshal(d.Tag)
+	fmt.Fprintf(&sb, "import(%s).then(() => import(%s)).then((router) => router.start(%s));\n", module, router, tag)
+	return sb.String()
+}
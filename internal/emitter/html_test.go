@@ -0,0 +1,36 @@
+package emitter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderImportMapBeforePreloads(t *testing.T) {
+	doc := &HTMLDocument{
+		Lang:    "en",
+		Tag:     "page-index",
+		Module:  "/chunks/page-index.js",
+		Preload: []string{"/chunks/page-index.js", "/vendor/lit/index.js"},
+		Head:    []string{`<script type="importmap">{"imports":{"lit":"/vendor/lit/index.js"}}</script>`},
+	}
+	out := doc.Render()
+
+	importMap := strings.Index(out, `<script type="importmap">`)
+	preload := strings.Index(out, `<link rel="modulepreload"`)
+	if importMap < 0 || preload < 0 {
+		t.Fatalf("Expected an import map and preloads, got:\n%s", out)
+	}
+	if importMap > preload {
+		t.Errorf("Expected the import map before the first modulepreload, got:\n%s", out)
+	}
+}
+
+func TestBootScriptStartsRouter(t *testing.T) {
+	doc := &HTMLDocument{Tag: "page-index", Module: "/chunks/page-index.js", Router: "/internal/router.js"}
+
+	expected := `document.querySelector("page-index").params = {};` + "\n" +
+		`import("/chunks/page-index.js").then(() => import("/internal/router.js")).then((router) => router.start("page-index"));` + "\n"
+	if got := doc.BootScript(); got != expected {
+		t.Errorf("Expected boot script:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,422 @@
+package emitter
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+)
+
+// Prerenderer renders pages to static HTML at build time.
+//
+// With shadow DOM enabled the output follows Lit's SSR format: every
+// component's template is written into a declarative shadow root and
+// annotated with the part markers that lit-element-hydrate-support uses to
+// hydrate the existing DOM instead of rendering it again. Without shadow DOM
+// the output is plain HTML which the components replace once they load.
+//
+// Parts whose value can't be computed at build time are left out of plain
+// HTML, so everything around them is still prerendered. Hydration needs
+// every part, so with shadow DOM a template with such a part is written as
+// plain HTML in the host's light DOM instead. The component then renders
+// into a shadow root of its own, which hides it, once it loads.
+type Prerenderer struct {
+	e         *Emitter
+	docs      map[string]*ast.Document // by absolute source path
+	templates map[string]*litTemplate
+	depth     int  // components currently being rendered
+	plain     bool // rendering without hydration markers
+}
+
+// maxComponentDepth stops a recursive component, used through a lazy
+// import, from rendering forever when its data never runs out.
+const maxComponentDepth = 100
+
+// NewPrerenderer creates a prerenderer that resolves components from docs,
+// which maps the absolute path of every JML file to its AST.
+func NewPrerenderer(e *Emitter, docs map[string]*ast.Document) *Prerenderer {
+	return &Prerenderer{
+		e:         e,
+		docs:      docs,
+		templates: make(map[string]*litTemplate),
+	}
+}
+
+// RenderPage renders a page for one set of route parameters.
+func (p *Prerenderer) RenderPage(doc *ast.Document, params map[string]string) (*HTMLDocument, error) {
+	if doc.DocType != ast.DocTypePage {
+		return nil, fmt.Errorf("%s is not a page", doc.SourceFile)
+	}
+
+	t, err := p.template(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newScope(nil)
+	paramValues := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		paramValues[k] = v
+	}
+	s.vars["params"] = paramValues
+
+	page := &HTMLDocument{
+		Tag:         p.e.TagName(doc.SourceFile),
+		Module:      p.e.ModuleURL(doc.SourceFile),
+		Params:      params,
+		Title:       p.pageText(doc, "title", s),
+		Description: p.pageText(doc, "description", s),
+		NoIndex:     p.pageText(doc, "noindex", s) == "true",
+		Lang:        p.pageText(doc, "lang", s),
+	}
+	if page.Lang == "" {
+		page.Lang = p.e.ctx.ProjectConfig.Lang
+	}
+	if page.Lang == "" {
+		page.Lang = "en"
+	}
+
+	var sb strings.Builder
+	if err := p.renderRoot(&sb, t, s); err != nil {
+		return nil, err
+	}
+	page.Body = sb.String()
+
+	return page, nil
+}
+
+// StaticParams evaluates the staticParams property of a dynamic page: a list
+// of objects with one entry per route parameter.
+func (p *Prerenderer) StaticParams(doc *ast.Document) ([]map[string]string, error) {
+	if doc.Root == nil {
+		return nil, nil
+	}
+	prop := doc.Root.Property("staticParams")
+	if prop == nil {
+		return nil, nil
+	}
+
+	v, err := evaluate(prop.Value, newScope(nil))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d:%d: staticParams must be a literal list: %w",
+			prop.File, prop.Line, prop.Column, err)
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s:%d:%d: staticParams must be a list of objects", prop.File, prop.Line, prop.Column)
+	}
+
+	var out []map[string]string
+	for _, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s:%d:%d: staticParams must be a list of objects", prop.File, prop.Line, prop.Column)
+		}
+		params := make(map[string]string, len(obj))
+		for k, v := range obj {
+			params[k] = toString(v)
+		}
+		out = append(out, params)
+	}
+	return out, nil
+}
+
+func (p *Prerenderer) pageText(doc *ast.Document, name string, s *scope) string {
+	prop := doc.Root.Property(name)
+	if prop == nil {
+		return ""
+	}
+	v, err := evaluate(prop.Value, s)
+	if err != nil {
+		return ""
+	}
+	return toString(v)
+}
+
+func (p *Prerenderer) template(doc *ast.Document) (*litTemplate, error) {
+	if t, ok := p.templates[doc.SourceFile]; ok {
+		return t, nil
+	}
+	t, err := newTemplateBuilder(p.e, doc).buildDocument()
+	if err != nil {
+		return nil, err
+	}
+	p.templates[doc.SourceFile] = t
+	return t, nil
+}
+
+// renderRoot renders what goes inside a component host: a declarative
+// shadow root holding the component's template, or the bare template when
+// shadow DOM is disabled.
+func (p *Prerenderer) renderRoot(sb *strings.Builder, t *litTemplate, s *scope) error {
+	if !p.ssr() {
+		return p.renderTemplate(sb, t, s)
+	}
+
+	var root strings.Builder
+	root.WriteString(`<template shadowroot="open" shadowrootmode="open">`)
+	root.WriteString("<!--lit-part " + digest(t.strings()) + "-->")
+	if err := p.renderTemplate(&root, t, s); err != nil {
+		if !errors.Is(err, errNotStatic) {
+			return err
+		}
+		p.plain = true
+		defer func() { p.plain = false }()
+		return p.renderTemplate(sb, t, s)
+	}
+	root.WriteString("<!--/lit-part--></template>")
+	sb.WriteString(root.String())
+	return nil
+}
+
+// ssr reports whether the output is meant to be hydrated.
+func (p *Prerenderer) ssr() bool {
+	return p.e.shadowDOM() && !p.plain
+}
+
+// skip reports whether a part that failed with err is left out. Only parts
+// whose value isn't known at build time are, and only in plain HTML, which
+// the component renders again anyway.
+func (p *Prerenderer) skip(err error) bool {
+	return !p.ssr() && errors.Is(err, errNotStatic)
+}
+
+func (p *Prerenderer) renderTemplate(sb *strings.Builder, t *litTemplate, s *scope) error {
+	ssr := p.ssr()
+
+	for _, seg := range t.segments {
+		switch seg.kind {
+		case staticSegment:
+			sb.WriteString(seg.text)
+		case nodeMarkerSegment:
+			if ssr {
+				fmt.Fprintf(sb, "<!--lit-node %d-->", seg.nodeIndex)
+			}
+		case deferHydrationSegment:
+			if ssr {
+				sb.WriteString(" defer-hydration")
+			}
+		case shadowRootSegment:
+			if err := p.renderComponent(sb, seg.component, s); err != nil {
+				return err
+			}
+		case partSegment:
+			if err := p.renderPart(sb, seg.part, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderComponent renders a nested component into its host element. When
+// its props cannot be computed at build time the host is left empty and the
+// component renders in the browser.
+func (p *Prerenderer) renderComponent(sb *strings.Builder, ref *componentRef, s *scope) error {
+	doc, ok := p.docs[ref.path]
+	if !ok {
+		return fmt.Errorf("component %s has not been compiled", ref.path)
+	}
+
+	if p.depth >= maxComponentDepth {
+		return fmt.Errorf("components are nested more than %d deep at %s; check for unbounded recursion", maxComponentDepth, ref.path)
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	t, err := p.template(doc)
+	if err != nil {
+		return err
+	}
+
+	props, err := evaluate(ref.props, s)
+	if err != nil {
+		if errors.Is(err, errNotStatic) {
+			return nil
+		}
+		return err
+	}
+
+	cs := newScope(nil)
+	cs.vars["props"] = props
+
+	return p.renderRoot(sb, t, cs)
+}
+
+func (p *Prerenderer) renderPart(sb *strings.Builder, part *litPart, s *scope) error {
+	ssr := p.ssr()
+
+	switch part.kind {
+	case propertyPart, eventPart:
+		// Properties and listeners only exist on the client.
+		return nil
+
+	case attributePart:
+		value, err := p.attributeValue(part, s)
+		if p.skip(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(sb, ` %s="%s"`, part.name, html.EscapeString(value))
+		return nil
+
+	case childPart:
+		v, err := evaluate(part.value, s)
+		if p.skip(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p.marker(sb, ssr, "<!--lit-part-->")
+		p.renderValue(sb, ssr, v)
+		p.marker(sb, ssr, "<!--/lit-part-->")
+		return nil
+
+	case conditionalPart:
+		v, err := evaluate(part.condition, s)
+		if p.skip(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		branch := part.then
+		if !truthy(v) {
+			branch = part.otherwise
+		}
+		if branch == nil {
+			p.marker(sb, ssr, "<!--lit-part--><!--/lit-part-->")
+			return nil
+		}
+		return p.renderTemplateResult(sb, branch, s)
+
+	case loopPart:
+		v, err := evaluate(part.iterable, s)
+		if err == nil {
+			if _, ok := v.([]interface{}); !ok {
+				err = errNotStatic
+			}
+		}
+		if p.skip(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		items := v.([]interface{})
+		p.marker(sb, ssr, "<!--lit-part-->")
+		for _, item := range items {
+			ls := newScope(s)
+			ls.vars[part.variable] = item
+			if err := p.renderTemplateResult(sb, part.body, ls); err != nil {
+				return err
+			}
+		}
+		p.marker(sb, ssr, "<!--/lit-part-->")
+		return nil
+	}
+
+	return fmt.Errorf("unknown template part")
+}
+
+// renderTemplateResult renders a nested html`...` value.
+func (p *Prerenderer) renderTemplateResult(sb *strings.Builder, t *litTemplate, s *scope) error {
+	ssr := p.ssr()
+	p.marker(sb, ssr, "<!--lit-part "+digest(t.strings())+"-->")
+	if err := p.renderTemplate(sb, t, s); err != nil {
+		return err
+	}
+	p.marker(sb, ssr, "<!--/lit-part-->")
+	return nil
+}
+
+// renderValue renders a primitive or a list of primitives the way a Lit
+// child part does.
+func (p *Prerenderer) renderValue(sb *strings.Builder, ssr bool, v interface{}) {
+	switch v := v.(type) {
+	case nil, undefined:
+	case string:
+		sb.WriteString(html.EscapeString(v))
+	case []interface{}:
+		for _, item := range v {
+			p.marker(sb, ssr, "<!--lit-part-->")
+			p.renderValue(sb, ssr, item)
+			p.marker(sb, ssr, "<!--/lit-part-->")
+		}
+	default:
+		sb.WriteString(html.EscapeString(toString(v)))
+	}
+}
+
+func (p *Prerenderer) marker(sb *strings.Builder, ssr bool, m string) {
+	if ssr {
+		sb.WriteString(m)
+	}
+}
+
+// attributeValue computes the string Lit sets on a bound attribute.
+func (p *Prerenderer) attributeValue(part *litPart, s *scope) (string, error) {
+	var classes []string
+
+	switch {
+	case part.classMap != nil:
+		for _, prop := range part.classMap.Properties {
+			v, err := evaluate(prop.Value, s)
+			if err != nil {
+				return "", err
+			}
+			if truthy(v) {
+				classes = append(classes, prop.Key)
+			}
+		}
+		return strings.Join(classes, " "), nil
+
+	case part.classList != nil:
+		for _, el := range part.classList.Elements {
+			v, err := evaluate(el, s)
+			if err != nil {
+				return "", err
+			}
+			if truthy(v) {
+				classes = append(classes, toString(v))
+			}
+		}
+		return strings.Join(classes, " "), nil
+	}
+
+	v, err := evaluate(part.value, s)
+	if err != nil {
+		return "", err
+	}
+	switch v.(type) {
+	case nil, undefined:
+		return "", nil
+	}
+	return toString(v), nil
+}
+
+// digest computes the template digest Lit uses to check that prerendered
+// markup was produced by the same template it is hydrating with. It mirrors
+// digestForTemplateResult in lit-html.
+func digest(strs []string) string {
+	hashes := [2]uint32{5381, 5381}
+	for _, s := range strs {
+		for i, c := range utf16.Encode([]rune(s)) {
+			hashes[i%2] = (hashes[i%2] * 33) ^ uint32(c)
+		}
+	}
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint32(buf[0:4], hashes[0])
+	binary.LittleEndian.PutUint32(buf[4:8], hashes[1])
+	return base64.StdEncoding.EncodeToString(buf[:])
+}
@@ -0,0 +1,120 @@
+package emitter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+func TestDigest(t *testing.T) {
+	// Values produced by lit-html's digestForTemplateResult for the same strings.
+	tests := []struct {
+		strings  []string
+		expected string
+	}{
+		{[]string{"<div class=\"a é\">", "</div>"}, "Cp5nyd6tYt0="},
+		{[]string{strings.Repeat("x", 1000)}, "Be0WzwXtFs8="},
+	}
+
+	for _, tt := range tests {
+		if got := digest(tt.strings); got != tt.expected {
+			t.Errorf("expected digest %s for %q, got %s", tt.expected, tt.strings, got)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	s := newScope(nil)
+	s.vars["props"] = map[string]interface{}{"name": "Ada", "count": float64(2)}
+
+	props := &ast.Identifier{Name: "props"}
+	tests := []struct {
+		expr     ast.Expression
+		expected string
+	}{
+		{&ast.BinaryExpression{Operator: "+", Left: &ast.StringLiteral{Value: "Hi "}, Right: &ast.MemberExpression{Object: props, Property: "name"}}, "Hi Ada"},
+		{&ast.BinaryExpression{Operator: "*", Left: &ast.MemberExpression{Object: props, Property: "count"}, Right: &ast.NumberLiteral{Value: 1.5}}, "3"},
+		{&ast.BinaryExpression{Operator: "||", Left: &ast.MemberExpression{Object: props, Property: "missing"}, Right: &ast.StringLiteral{Value: "fallback"}}, "fallback"},
+		{&ast.TemplateLiteral{Quasis: []string{"n=", ""}, Expressions: []ast.Expression{&ast.MemberExpression{Object: props, Property: "count"}}}, "n=2"},
+	}
+
+	for _, tt := range tests {
+		v, err := evaluate(tt.expr, s)
+		if err != nil {
+			t.Errorf("expected no error evaluating %s, got %v", tt.expr, err)
+			continue
+		}
+		if got := toString(v); got != tt.expected {
+			t.Errorf("expected %s to evaluate to %q, got %q", tt.expr, tt.expected, got)
+		}
+	}
+
+	call := &ast.CallExpression{Callee: &ast.Identifier{Name: "format"}}
+	if _, err := evaluate(call, s); err != errNotStatic {
+		t.Errorf("expected errNotStatic for %s, got %v", call, err)
+	}
+}
+
+func TestRenderPagePartlyStatic(t *testing.T) {
+	root := t.TempDir()
+	text := func(value ast.Expression) *ast.Element {
+		return &ast.Element{Name: "Text", Properties: []*ast.Property{{Name: "content", Value: value}}}
+	}
+	doc := &ast.Document{
+		DocType:    ast.DocTypePage,
+		Name:       "index",
+		SourceFile: filepath.Join(root, "app", "index.jml"),
+		Root: &ast.Element{
+			Name:       "Page",
+			Properties: []*ast.Property{{Name: "lang", Value: &ast.StringLiteral{Value: "sw"}}},
+			Children: []ast.Node{
+				text(&ast.StringLiteral{Value: "Karibu"}),
+				text(&ast.CallExpression{Callee: &ast.Identifier{Name: "now"}}),
+			},
+		},
+	}
+
+	for _, shadowDOM := range []bool{false, true} {
+		config := core.DefaultProjectConfig()
+		config.Build.ShadowDOM = shadowDOM
+		ctx := &core.JawtContext{
+			ProjectConfig: config,
+			Paths:         &core.ProjectPaths{ProjectRoot: root, AppDir: filepath.Join(root, "app")},
+		}
+
+		page, err := NewPrerenderer(NewEmitter(ctx), nil).RenderPage(doc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(page.Body, "Karibu") {
+			t.Errorf("Expected the static text to be prerendered with shadowDOM %v, got %q", shadowDOM, page.Body)
+		}
+		if strings.Contains(page.Body, "lit-part") || strings.Contains(page.Body, "<template") {
+			t.Errorf("Expected plain markup for a template that can't be hydrated, got %q", page.Body)
+		}
+		if page.Lang != "sw" {
+			t.Errorf("Expected lang sw, got %s", page.Lang)
+		}
+	}
+}
+
+func TestExpressionString(t *testing.T) {
+	x := &ast.Identifier{Name: "x"}
+	tests := []struct {
+		expr     ast.Expression
+		expected string
+	}{
+		{&ast.UnaryExpression{Operator: "-", Operand: &ast.UnaryExpression{Operator: "-", Operand: x}}, "-(-x)"},
+		{&ast.UnaryExpression{Operator: "!", Operand: x}, "!x"},
+		{&ast.StringLiteral{Value: "bell\a \U0001F600 \u2028\"q\"\\"}, `"bell\x07 😀 \u2028\"q\"\\"`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.expr.String(); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
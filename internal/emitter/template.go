@@ -0,0 +1,512 @@
+package emitter
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yasufadhili/jawt/internal/ast"
+)
+
+// A litTemplate is a JML element tree compiled to the shape of a Lit
+// html`...` template: static strings interleaved with bound parts.
+//
+// The same value is used to emit the TypeScript template and to prerender
+// it in Go, so the static strings (and therefore the template digest Lit
+// checks during hydration) are identical on both sides.
+type litTemplate struct {
+	segments []segment
+	nodes    int // elements and child-part markers seen so far, as Lit counts them
+}
+
+type segmentKind int
+
+const (
+	staticSegment segmentKind = iota
+	partSegment
+
+	// The remaining kinds only affect prerendered output. They are
+	// invisible in the TypeScript template.
+	nodeMarkerSegment     // <!--lit-node N--> before an element with bindings
+	deferHydrationSegment // defer-hydration attribute on a nested component host
+	shadowRootSegment     // the declarative shadow root of a nested component
+)
+
+type segment struct {
+	kind      segmentKind
+	text      string
+	part      *litPart
+	nodeIndex int
+	component *componentRef
+}
+
+// componentRef identifies a user component used inside a template.
+type componentRef struct {
+	path  string // absolute path of the component's JML file
+	props *ast.ObjectLiteral
+}
+
+type partKind int
+
+const (
+	childPart partKind = iota
+	attributePart
+	propertyPart
+	eventPart
+	conditionalPart
+	loopPart
+)
+
+type litPart struct {
+	kind partKind
+
+	// binding is the template text that introduces the part, e.g. ` class=`.
+	binding string
+	name    string
+	value   ast.Expression
+
+	// Class bindings built from a style list or map instead of value.
+	classMap  *ast.ObjectLiteral
+	classList *ast.ArrayLiteral
+
+	// conditionalPart
+	condition ast.Expression
+	then      *litTemplate
+	otherwise *litTemplate
+
+	// loopPart
+	variable string
+	iterable ast.Expression
+	body     *litTemplate
+}
+
+func (t *litTemplate) static(s string) {
+	t.segments = append(t.segments, segment{kind: staticSegment, text: s})
+}
+
+func (t *litTemplate) bind(p *litPart) {
+	t.segments = append(t.segments, segment{kind: partSegment, part: p})
+	if p.kind == childPart || p.kind == conditionalPart || p.kind == loopPart {
+		t.nodes++
+	}
+}
+
+// openElement reserves the node index for the next element.
+func (t *litTemplate) openElement() int {
+	idx := t.nodes
+	t.nodes++
+	return idx
+}
+
+// strings returns the static strings of the template as TypeScript sees them.
+func (t *litTemplate) strings() []string {
+	var out []string
+	var cur strings.Builder
+	for _, seg := range t.segments {
+		switch seg.kind {
+		case staticSegment:
+			cur.WriteString(seg.text)
+		case partSegment:
+			cur.WriteString(seg.part.binding)
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+	}
+	return append(out, cur.String())
+}
+
+// parts returns the bound parts in template order.
+func (t *litTemplate) parts() []*litPart {
+	var out []*litPart
+	for _, seg := range t.segments {
+		if seg.kind == partSegment {
+			out = append(out, seg.part)
+		}
+	}
+	return out
+}
+
+// typescript renders the template as a Lit html`...` expression.
+func (t *litTemplate) typescript() string {
+	strs := t.strings()
+	parts := t.parts()
+
+	var sb strings.Builder
+	sb.WriteString("html`")
+	for i, s := range strs {
+		sb.WriteString(ast.EscapeTemplateText(s))
+		if i < len(parts) {
+			sb.WriteString("${")
+			sb.WriteString(parts[i].typescript())
+			sb.WriteString("}")
+		}
+	}
+	sb.WriteString("`")
+	return sb.String()
+}
+
+func (p *litPart) typescript() string {
+	switch p.kind {
+	case conditionalPart:
+		otherwise := "nothing"
+		if p.otherwise != nil {
+			otherwise = p.otherwise.typescript()
+		}
+		return fmt.Sprintf("(%s) ? %s : %s", p.condition, p.then.typescript(), otherwise)
+	case loopPart:
+		return fmt.Sprintf("(%s).map((%s) => %s)", p.iterable, p.variable, p.body.typescript())
+	}
+	switch {
+	case p.classMap != nil:
+		return "classMap(" + p.classMap.String() + ")"
+	case p.classList != nil:
+		return p.classList.String() + ".filter(Boolean).join(' ')"
+	default:
+		return p.value.String()
+	}
+}
+
+// templateBuilder compiles the element tree of one document into litTemplates.
+type templateBuilder struct {
+	e          *Emitter
+	doc        *ast.Document
+	components map[string]*ast.Import
+	classMap   bool // whether any template uses the classMap directive
+}
+
+func newTemplateBuilder(e *Emitter, doc *ast.Document) *templateBuilder {
+	b := &templateBuilder{
+		e:          e,
+		doc:        doc,
+		components: make(map[string]*ast.Import),
+	}
+	for _, imp := range doc.Imports {
+		if imp.Kind == ast.ImportComponent {
+			b.components[imp.Name] = imp
+		}
+	}
+	return b
+}
+
+// buildDocument compiles the render template of the document. For pages
+// the Page element itself is not rendered, only what it contains.
+func (b *templateBuilder) buildDocument() (*litTemplate, error) {
+	root := b.doc.Root
+	if root == nil {
+		return nil, fmt.Errorf("%s: document has no root element", b.doc.SourceFile)
+	}
+
+	if b.doc.DocType == ast.DocTypePage {
+		if root.Name != pageElement {
+			return nil, b.errorf(root.Position, "the root element of a page must be %s, found %s", pageElement, root.Name)
+		}
+		return b.build(root.Children)
+	}
+
+	return b.build([]ast.Node{root})
+}
+
+func (b *templateBuilder) build(nodes []ast.Node) (*litTemplate, error) {
+	t := &litTemplate{}
+	for _, n := range nodes {
+		if err := b.node(t, n); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (b *templateBuilder) node(t *litTemplate, n ast.Node) error {
+	switch n := n.(type) {
+	case *ast.Element:
+		return b.element(t, n)
+	case *ast.IfBlock:
+		then, err := b.build(n.Then)
+		if err != nil {
+			return err
+		}
+		part := &litPart{kind: conditionalPart, condition: n.Condition, then: then}
+		if len(n.Else) > 0 {
+			if part.otherwise, err = b.build(n.Else); err != nil {
+				return err
+			}
+		}
+		t.bind(part)
+	case *ast.ForLoop:
+		body, err := b.build(n.Body)
+		if err != nil {
+			return err
+		}
+		t.bind(&litPart{kind: loopPart, variable: n.Variable, iterable: n.Iterable, body: body})
+	default:
+		return b.errorf(n.Pos(), "unexpected %s in element tree", n)
+	}
+	return nil
+}
+
+func (b *templateBuilder) element(t *litTemplate, el *ast.Element) error {
+	if bi, ok := builtins[el.Name]; ok {
+		return b.builtinElement(t, el, bi)
+	}
+	if imp, ok := b.components[el.Name]; ok {
+		return b.componentElement(t, el, imp)
+	}
+	if el.Name == pageElement {
+		return b.errorf(el.Position, "%s can only be used as the root element of a page", pageElement)
+	}
+	return b.errorf(el.Position, "unknown component %s; import it with `import component %s from \"...\"`", el.Name, el.Name)
+}
+
+func (b *templateBuilder) builtinElement(t *litTemplate, el *ast.Element, bi builtin) error {
+	var attrs, events []*ast.Property
+	var content *ast.Property
+	for _, p := range el.Properties {
+		switch {
+		case isContentProperty(p.Name):
+			content = p
+		case isEventProperty(p.Name):
+			events = append(events, p)
+		default:
+			attrs = append(attrs, p)
+		}
+	}
+
+	bound := len(events) > 0
+	for _, p := range attrs {
+		if p.Name == "style" {
+			if _, ok := staticClasses(p.Value); !ok {
+				bound = true
+			}
+		} else if !isStaticAttribute(p.Value) {
+			bound = true
+		}
+	}
+
+	idx := t.openElement()
+	if bound {
+		t.segments = append(t.segments, segment{kind: nodeMarkerSegment, nodeIndex: idx})
+	}
+
+	t.static("<" + bi.tag)
+	for _, p := range attrs {
+		if p.Name == "style" {
+			b.style(t, p.Value)
+			continue
+		}
+		if p.Name == placeholderProperty && bi.tag == "img" {
+			if err := b.placeholder(t, p); err != nil {
+				return err
+			}
+			continue
+		}
+		b.attribute(t, attributeName(p.Name), p.Value)
+	}
+	for _, p := range events {
+		t.bind(&litPart{kind: eventPart, binding: " @" + eventName(p.Name) + "=", name: eventName(p.Name), value: p.Value})
+	}
+	t.static(">")
+
+	if bi.void {
+		if content != nil || len(el.Children) > 0 {
+			return b.errorf(el.Position, "%s cannot have content or children", el.Name)
+		}
+		return nil
+	}
+
+	if content != nil {
+		if lit, ok := content.Value.(*ast.StringLiteral); ok {
+			t.static(html.EscapeString(lit.Value))
+		} else {
+			t.bind(&litPart{kind: childPart, value: content.Value})
+		}
+	}
+
+	for _, child := range el.Children {
+		if err := b.node(t, child); err != nil {
+			return err
+		}
+	}
+
+	t.static("</" + bi.tag + ">")
+	return nil
+}
+
+// attribute writes a static attribute when the value is a literal and binds
+// an attribute part otherwise.
+func (b *templateBuilder) attribute(t *litTemplate, name string, value ast.Expression) {
+	switch v := value.(type) {
+	case *ast.StringLiteral:
+		t.static(fmt.Sprintf(` %s="%s"`, name, html.EscapeString(v.Value)))
+	case *ast.NumberLiteral:
+		t.static(fmt.Sprintf(` %s="%s"`, name, v.Raw))
+	case *ast.BooleanLiteral:
+		if v.Value {
+			t.static(" " + name)
+		}
+	default:
+		t.bind(&litPart{kind: attributePart, binding: " " + name + "=", name: name, value: value})
+	}
+}
+
+// placeholder writes the inline style that shows an image placeholder. An
+// empty string turns the placeholder off.
+func (b *templateBuilder) placeholder(t *litTemplate, p *ast.Property) error {
+	lit, ok := p.Value.(*ast.StringLiteral)
+	if !ok {
+		return b.errorf(p.Position, "placeholder must be a string")
+	}
+	if lit.Value != "" {
+		t.static(fmt.Sprintf(` style="%s"`, html.EscapeString(placeholderStyle(lit.Value))))
+	}
+	return nil
+}
+
+// style writes the class attribute for a style value. Lists and maps are
+// turned into a classMap so that toggling a condition only adds or removes
+// that one class; lists that can't be expressed that way are joined instead.
+func (b *templateBuilder) style(t *litTemplate, value ast.Expression) {
+	if classes, ok := staticClasses(value); ok {
+		if classes != "" {
+			t.static(fmt.Sprintf(` class="%s"`, html.EscapeString(classes)))
+		}
+		return
+	}
+
+	part := &litPart{kind: attributePart, binding: " class=", name: "class", value: value}
+	switch v := value.(type) {
+	case *ast.ObjectLiteral:
+		part.classMap = splitClassKeys(v)
+	case *ast.ArrayLiteral:
+		if m, ok := classListToMap(v); ok {
+			part.classMap = m
+		} else {
+			part.classList = v
+		}
+	}
+	if part.classMap != nil {
+		b.classMap = true
+	}
+	t.bind(part)
+}
+
+func (b *templateBuilder) componentElement(t *litTemplate, el *ast.Element, imp *ast.Import) error {
+	if imp.ResolvedPath == "" {
+		return b.errorf(el.Position, "component %s imported from %q could not be resolved", el.Name, imp.Path)
+	}
+
+	props := &ast.ObjectLiteral{Position: el.Position}
+	for _, p := range el.Properties {
+		props.Properties = append(props.Properties, &ast.ObjectProperty{
+			Position: p.Position,
+			Key:      p.Name,
+			Value:    p.Value,
+		})
+	}
+	ref := &componentRef{path: imp.ResolvedPath, props: props}
+	tag := b.e.TagName(imp.ResolvedPath)
+
+	idx := t.openElement()
+	t.segments = append(t.segments, segment{kind: nodeMarkerSegment, nodeIndex: idx})
+	t.static("<" + tag)
+	t.segments = append(t.segments, segment{kind: deferHydrationSegment})
+	t.bind(&litPart{kind: propertyPart, binding: " .props=", name: "props", value: props})
+	t.static(">")
+	t.segments = append(t.segments, segment{kind: shadowRootSegment, component: ref})
+
+	for _, child := range el.Children {
+		if err := b.node(t, child); err != nil {
+			return err
+		}
+	}
+
+	t.static("</" + tag + ">")
+	return nil
+}
+
+func (b *templateBuilder) errorf(pos ast.Position, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d:%d: %s", pos.File, pos.Line, pos.Column, fmt.Sprintf(format, args...))
+}
+
+// staticClasses returns the classes of a style value that doesn't depend on
+// anything: a string, or a list of strings.
+func staticClasses(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return strings.Join(strings.Fields(e.Value), " "), true
+	case *ast.ArrayLiteral:
+		var classes []string
+		for _, el := range e.Elements {
+			lit, ok := el.(*ast.StringLiteral)
+			if !ok {
+				return "", false
+			}
+			classes = append(classes, strings.Fields(lit.Value)...)
+		}
+		return strings.Join(classes, " "), true
+	}
+	return "", false
+}
+
+// classListToMap converts a style list such as ["card", active && "ring"]
+// into the equivalent class map, if every entry has a known shape.
+func classListToMap(list *ast.ArrayLiteral) (*ast.ObjectLiteral, bool) {
+	m := &ast.ObjectLiteral{Position: list.Position}
+	index := make(map[string]*ast.ObjectProperty)
+
+	add := func(classes ast.Expression, cond ast.Expression) bool {
+		if _, ok := classes.(*ast.NullLiteral); ok {
+			return true
+		}
+		lit, ok := classes.(*ast.StringLiteral)
+		if !ok {
+			return false
+		}
+		for _, class := range strings.Fields(lit.Value) {
+			if prev, ok := index[class]; ok {
+				prev.Value = &ast.BinaryExpression{Position: cond.Pos(), Operator: "||", Left: prev.Value, Right: cond}
+				continue
+			}
+			p := &ast.ObjectProperty{Position: lit.Position, Key: class, Value: cond}
+			index[class] = p
+			m.Properties = append(m.Properties, p)
+		}
+		return true
+	}
+
+	for _, el := range list.Elements {
+		var ok bool
+		switch e := el.(type) {
+		case *ast.StringLiteral:
+			ok = add(e, &ast.BooleanLiteral{Position: e.Position, Value: true})
+		case *ast.BinaryExpression:
+			ok = e.Operator == "&&" && add(e.Right, e.Left)
+		case *ast.ConditionalExpression:
+			ok = add(e.Consequent, e.Test) &&
+				add(e.Alternate, &ast.UnaryExpression{Position: e.Position, Operator: "!", Operand: e.Test})
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+// splitClassKeys returns a class map where every key is a single class, since
+// classMap cannot toggle "a b" as a unit.
+func splitClassKeys(obj *ast.ObjectLiteral) *ast.ObjectLiteral {
+	out := &ast.ObjectLiteral{Position: obj.Position}
+	for _, p := range obj.Properties {
+		for _, class := range strings.Fields(p.Key) {
+			out.Properties = append(out.Properties, &ast.ObjectProperty{Position: p.Position, Key: class, Value: p.Value})
+		}
+	}
+	return out
+}
+
+func isStaticAttribute(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.StringLiteral, *ast.NumberLiteral, *ast.BooleanLiteral:
+		return true
+	}
+	return false
+}
@@ -19,6 +19,9 @@ type Options struct {
 	RestartDelay     time.Duration `json:"restart_delay"`
 	MaxRestarts      int           `json:"max_restarts"`
 
+	// Timeout limits how long Run waits for the command. Zero means no limit.
+	Timeout time.Duration `json:"timeout"`
+
 	// Handlers
 	OutputHandler func(string) `json:"-"`
 	ErrorHandler  func(error)  `json:"-"`
@@ -60,6 +63,12 @@ func (po Options) WithRestart(enabled bool, delay time.Duration, maxRestarts int
 	return po
 }
 
+// WithTimeout sets how long the command may run
+func (po Options) WithTimeout(timeout time.Duration) Options {
+	po.Timeout = timeout
+	return po
+}
+
 // WithOutputHandler sets the output handler
 func (po Options) WithOutputHandler(handler func(string)) Options {
 	po.OutputHandler = handler
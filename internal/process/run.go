@@ -0,0 +1,80 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/yasufadhili/jawt/internal/core"
+)
+
+// Run runs a command to completion, streaming its output to the logger
+// through ProcessLogger. It fails if the command exits with an error or is
+// still running after options.Timeout.
+func Run(ctx context.Context, name string, options Options, logger core.Logger) error {
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, options.Command, options.Args...)
+	cmd.Dir = options.WorkingDir
+	cmd.Env = options.Env
+	// Children of a killed shell can keep its output open
+	cmd.WaitDelay = time.Second
+
+	// The output goes through pipes of our own rather than StdoutPipe, so
+	// that Wait doesn't depend on every child closing them
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		ProcessLogger(stdout, logger, name)
+		// The scanner gives up on overlong lines; keep the command from blocking
+		io.Copy(io.Discard, stdout)
+	}()
+
+	go func() {
+		defer wg.Done()
+		ProcessLogger(stderr, logger, name+"-err")
+		io.Copy(io.Discard, stderr)
+	}()
+
+	err := cmd.Run()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out after %s", name, options.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+// ShellOptions returns options for running a command line through the
+// system shell.
+func ShellOptions(command, workingDir string) Options {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	return DefaultProcessOptions().
+		WithCommand(shell, flag, command).
+		WithWorkingDir(workingDir).
+		WithRestart(false, 0, 0)
+}